@@ -0,0 +1,110 @@
+package gitlab
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func TestArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		opts    []*gitlabdata.ArchiveOptions
+		path    string
+		query   url.Values
+	}{
+		{
+			name:    "default",
+			project: "42",
+			path:    "/projects/42/repository/archive.zip",
+			query:   url.Values{"sha": {"v1.0.0"}},
+		},
+		{
+			name:    "nil-options",
+			project: "42",
+			opts:    []*gitlabdata.ArchiveOptions{nil},
+			path:    "/projects/42/repository/archive.zip",
+			query:   url.Values{"sha": {"v1.0.0"}},
+		},
+		{
+			name:    "format",
+			project: "42",
+			opts:    []*gitlabdata.ArchiveOptions{{Format: gitlabdata.String(gitlabdata.ArchiveFormatTarBz2)}},
+			path:    "/projects/42/repository/archive.tar.bz2",
+			query:   url.Values{"sha": {"v1.0.0"}},
+		},
+		{
+			name:    "path",
+			project: "42",
+			opts:    []*gitlabdata.ArchiveOptions{{Format: gitlabdata.String(gitlabdata.ArchiveFormatTarGz), Path: gitlabdata.String("sub/dir")}},
+			path:    "/projects/42/repository/archive.tar.gz",
+			query:   url.Values{"sha": {"v1.0.0"}, "path": {"sub/dir"}},
+		},
+		{
+			name:    "project-path",
+			project: "g/p",
+			opts:    []*gitlabdata.ArchiveOptions{{Format: gitlabdata.String(gitlabdata.ArchiveFormatTar)}},
+			path:    "/projects/42/repository/archive.tar",
+			query:   url.Values{"sha": {"v1.0.0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := apiServer(t, map[string]string{
+				"GET /projects/g%2Fp": `{"id":42,"path_with_namespace":"g/p"}`,
+				"GET " + tt.path:      "archive data",
+			})
+			client := NewAPIAccess(nil, srv.URL).Client("token")
+
+			archive, err := client.Archive(context.Background(), tt.project, "v1.0.0", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer archive.Close()
+			data, err := ioutil.ReadAll(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "archive data" {
+				t.Errorf("got archive %q", data)
+			}
+
+			// project paths are resolved into IDs first
+			archiveRequest := (*requests)[0]
+			if tt.project != "42" {
+				if len(*requests) != 2 {
+					t.Fatalf("got %d requests, want project info and archive ones", len(*requests))
+				}
+				checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp", url.Values{})
+				archiveRequest = (*requests)[1]
+			}
+			checkRequest(t, archiveRequest, http.MethodGet, tt.path, tt.query)
+		})
+	}
+}
+
+func TestArchiveErrors(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	_, err := client.Archive(context.Background(), "42", "v1.0.0", &gitlabdata.ArchiveOptions{Format: gitlabdata.String("rar")})
+	if err == nil {
+		t.Error("unsupported format must be rejected")
+	}
+	if len(*requests) != 0 {
+		t.Errorf("got %d requests for unsupported format", len(*requests))
+	}
+
+	if _, err := client.Archive(context.Background(), "g/missing", "v1.0.0"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found project", err)
+	}
+	if _, err := client.Archive(context.Background(), "42", "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found archive", err)
+	}
+}
@@ -0,0 +1,132 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Authenticator puts credentials of a user into requests to gitlab API
+type Authenticator interface {
+	// Authenticate adds credentials to the request
+	Authenticate(ctx context.Context, req *http.Request) error
+
+	// Identity returns a stable key of the user the credentials belong to. It is used to split rate limiter
+	// budgets and cached responses of different users
+	Identity() string
+}
+
+// PrivateToken authenticates with personal, project or group access token passed via PRIVATE-TOKEN header
+func PrivateToken(token string) Authenticator {
+	return headerAuth{header: "PRIVATE-TOKEN", token: token}
+}
+
+// JobToken authenticates with CI job token (CI_JOB_TOKEN) passed via JOB-TOKEN header
+func JobToken(token string) Authenticator {
+	return headerAuth{header: "JOB-TOKEN", token: token}
+}
+
+// DeployToken authenticates with deploy token passed via Deploy-Token header. Keep in mind gitlab only accepts
+// deploy tokens in a few APIs, such as package registries
+func DeployToken(token string) Authenticator {
+	return headerAuth{header: "Deploy-Token", token: token}
+}
+
+// BearerToken authenticates with OAuth2 access token passed via Authorization header
+func BearerToken(token string) Authenticator {
+	return headerAuth{header: "Authorization", prefix: "Bearer ", token: token}
+}
+
+type headerAuth struct {
+	header string
+	prefix string
+	token  string
+}
+
+func (a headerAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	req.Header.Set(a.header, a.prefix+a.token)
+	return nil
+}
+
+func (a headerAuth) Identity() string {
+	return a.header + ":" + a.token
+}
+
+// TokenSource provides OAuth2 access tokens. It is meant to be a thin adapter over oauth2.TokenSource or a similar
+// provider
+type TokenSource interface {
+	// Token returns a valid access token
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc turns a function into TokenSource
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token to implement TokenSource
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// OAuth2 authenticates with OAuth2 access tokens got from the source for every request, so the source is free to
+// refresh them. Access tokens change over time, so the identity of the user is to be given explicitly: it is
+// a stable name of the user the source gets tokens for, such as user name or OAuth2 client ID
+func OAuth2(identity string, source TokenSource) Authenticator {
+	return &oauth2Auth{identity: identity, source: source}
+}
+
+type oauth2Auth struct {
+	identity string
+	source   TokenSource
+}
+
+func (a *oauth2Auth) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := a.source.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get oauth2 access token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *oauth2Auth) Identity() string {
+	return "oauth2:" + a.identity
+}
+
+// RefreshFunc gets a new access token along with its expiry moment. Zero expiry means the token never expires
+type RefreshFunc func(ctx context.Context) (token string, expiry time.Time, err error)
+
+// NewRefreshingTokenSource creates a token source which keeps an access token and refreshes it with the given function
+// when it is about to expire in less than margin. It is safe for concurrent use
+func NewRefreshingTokenSource(refresh RefreshFunc, margin time.Duration) TokenSource {
+	return &refreshingTokenSource{
+		refresh: refresh,
+		margin:  margin,
+	}
+}
+
+type refreshingTokenSource struct {
+	lock    sync.Mutex
+	refresh RefreshFunc
+	margin  time.Duration
+	token   string
+	expiry  time.Time
+}
+
+func (s *refreshingTokenSource) Token(ctx context.Context) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.token) > 0 && (s.expiry.IsZero() || time.Now().Add(s.margin).Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	token, expiry, err := s.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+	s.expiry = expiry
+	return token, nil
+}
@@ -0,0 +1,98 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAuthenticators(t *testing.T) {
+	source := TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return "access", nil
+	})
+
+	tests := []struct {
+		name     string
+		auth     Authenticator
+		header   string
+		value    string
+		identity string
+	}{
+		{"private", PrivateToken("secret"), "PRIVATE-TOKEN", "secret", "PRIVATE-TOKEN:secret"},
+		{"job", JobToken("secret"), "JOB-TOKEN", "secret", "JOB-TOKEN:secret"},
+		{"deploy", DeployToken("secret"), "Deploy-Token", "secret", "Deploy-Token:secret"},
+		{"bearer", BearerToken("secret"), "Authorization", "Bearer secret", "Authorization:secret"},
+		{"oauth2", OAuth2("user", source), "Authorization", "Bearer access", "oauth2:user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://gitlab.example.com/api/v4/projects", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.auth.Authenticate(context.Background(), req); err != nil {
+				t.Fatal(err)
+			}
+			if value := req.Header.Get(tt.header); value != tt.value {
+				t.Errorf("%s = %q, want %q", tt.header, value, tt.value)
+			}
+			if identity := tt.auth.Identity(); identity != tt.identity {
+				t.Errorf("identity = %q, want %q", identity, tt.identity)
+			}
+		})
+	}
+}
+
+func TestOAuth2Identity(t *testing.T) {
+	source := TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return "access", nil
+	})
+
+	// identities must be stable across authenticators of the same user and differ for different users
+	if OAuth2("user", source).Identity() != OAuth2("user", source).Identity() {
+		t.Error("identities of the same user differ")
+	}
+	if OAuth2("user", source).Identity() == OAuth2("other", source).Identity() {
+		t.Error("identities of different users are the same")
+	}
+}
+
+func TestRefreshingTokenSource(t *testing.T) {
+	var calls int
+	source := NewRefreshingTokenSource(func(ctx context.Context) (string, time.Time, error) {
+		calls++
+		if calls == 3 {
+			return "", time.Time{}, errors.New("refresh failed")
+		}
+		return "token", time.Now().Add(time.Hour), nil
+	}, 30*time.Minute)
+
+	for i := 0; i < 3; i++ {
+		token, err := source.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "token" {
+			t.Fatalf("unexpected token %q", token)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("token was refreshed %d times, expected once", calls)
+	}
+
+	expiring := NewRefreshingTokenSource(func(ctx context.Context) (string, time.Time, error) {
+		calls++
+		if calls == 3 {
+			return "", time.Time{}, errors.New("refresh failed")
+		}
+		return "token", time.Now().Add(time.Minute), nil
+	}, 30*time.Minute)
+	if _, err := expiring.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expiring.Token(context.Background()); err == nil {
+		t.Error("token about to expire must be refreshed")
+	}
+}
@@ -0,0 +1,172 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// apiRequest is a request recorded by apiServer
+type apiRequest struct {
+	method string
+	path   string // escaped
+	query  url.Values
+	body   map[string]interface{}
+}
+
+// apiServer responds with bodies of routes keyed by a method and an escaped path, e.g.
+// "GET /projects/g%2Fp/repository/branches", an empty body stands for 204 No Content. Unknown routes get 404.
+// Requests are recorded
+func apiServer(t *testing.T, routes map[string]string) (*httptest.Server, *[]apiRequest) {
+	var requests []apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := apiRequest{
+			method: r.Method,
+			path:   r.URL.EscapedPath(),
+			query:  r.URL.Query(),
+		}
+		if r.Header.Get("Content-Type") == "application/json" {
+			if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+				t.Errorf("failed to decode body of %s %s: %v", r.Method, r.URL, err)
+			}
+		}
+		requests = append(requests, req)
+
+		body, ok := routes[req.method+" "+req.path]
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case len(body) == 0:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+// checkRequest checks the request was made with the given method, escaped path and query. Pagination parameters
+// are not compared
+func checkRequest(t *testing.T, req apiRequest, method, path string, query url.Values) {
+	t.Helper()
+
+	if req.method != method || req.path != path {
+		t.Errorf("got request %s %s, want %s %s", req.method, req.path, method, path)
+	}
+	got := url.Values{}
+	for key, values := range req.query {
+		if key != "page" && key != "per_page" {
+			got[key] = values
+		}
+	}
+	if got.Encode() != query.Encode() {
+		t.Errorf("%s %s got query %s, want %s", method, path, got.Encode(), query.Encode())
+	}
+}
+
+func TestBranches(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/branches": `[
+			{"name":"master","default":true,"protected":true,"commit":{"id":"aaa"}},
+			{"name":"feature/x","merged":true,"commit":{"id":"bbb"}}
+		]`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	branches, err := client.Branches(context.Background(), "g/p", "^feat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 2 {
+		t.Fatalf("got %d branches", len(branches))
+	}
+	if b := branches[0]; b.Name != "master" || !b.Default || !b.Protected || b.Commit.ID != "aaa" {
+		t.Errorf("unexpected branch %+v", b)
+	}
+	if b := branches[1]; b.Name != "feature/x" || !b.Merged || b.Default || b.Commit.ID != "bbb" {
+		t.Errorf("unexpected branch %+v", b)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/branches", url.Values{"search": {"^feat"}})
+
+	// no search parameter without search
+	iter := client.BranchesIter(context.Background(), "g/p", "", &gitlabdata.ListOptions{PerPage: 1, MaxItems: 1})
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Value().Name)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "master" {
+		t.Errorf("got branches %v", names)
+	}
+	checkRequest(t, (*requests)[1], http.MethodGet, "/projects/g%2Fp/repository/branches", url.Values{})
+	if perPage := (*requests)[1].query.Get("per_page"); perPage != "1" {
+		t.Errorf("got per_page %s", perPage)
+	}
+}
+
+func TestBranch(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/branches/feature%2Fx": `{"name":"feature/x","can_push":true,"commit":{"id":"bbb"}}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	branch, err := client.Branch(context.Background(), "g/p", "feature/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch.Name != "feature/x" || !branch.CanPush || branch.Commit.ID != "bbb" {
+		t.Errorf("unexpected branch %+v", branch)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/branches/feature%2Fx", url.Values{})
+
+	if _, err := client.Branch(context.Background(), "g/p", "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
+
+func TestCreateBranch(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"POST /projects/g%2Fp/repository/branches": `{"name":"feature/x","commit":{"id":"aaa"}}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	branch, err := client.CreateBranch(context.Background(), "g/p", "feature/x", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch.Name != "feature/x" || branch.Commit.ID != "aaa" {
+		t.Errorf("unexpected branch %+v", branch)
+	}
+	checkRequest(t, (*requests)[0], http.MethodPost, "/projects/g%2Fp/repository/branches",
+		url.Values{"branch": {"feature/x"}, "ref": {"v1.0.0"}})
+}
+
+func TestDeleteBranches(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"DELETE /projects/g%2Fp/repository/branches/feature%2Fx": "",
+		"DELETE /projects/g%2Fp/repository/merged_branches":      `{"message":"202 Accepted"}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	if err := client.DeleteBranch(context.Background(), "g/p", "feature/x"); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, (*requests)[0], http.MethodDelete, "/projects/g%2Fp/repository/branches/feature%2Fx", url.Values{})
+
+	if err := client.DeleteMergedBranches(context.Background(), "g/p"); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, (*requests)[1], http.MethodDelete, "/projects/g%2Fp/repository/merged_branches", url.Values{})
+
+	if err := client.DeleteBranch(context.Background(), "g/p", "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
@@ -0,0 +1,144 @@
+package gitlab
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// maxCachedBodySize limits the size of response bodies to be cached
+const maxCachedBodySize = 1 << 20
+
+// Cache keeps GET responses to revalidate them with ETag instead of downloading them again. Implementations must be
+// safe for concurrent use
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+}
+
+// CachedResponse is a response kept in the cache
+type CachedResponse struct {
+	ETag   string
+	Header http.Header
+	Body   []byte
+}
+
+// NewMemoryCache creates in-memory cache keeping up to capacity least recently used responses
+func NewMemoryCache(capacity int) Cache {
+	return &memoryCache{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+type memoryCache struct {
+	lock     sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key  string
+	resp *CachedResponse
+}
+
+func (c *memoryCache) Get(key string) (*CachedResponse, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(item)
+	return item.Value.(*memoryCacheItem).resp, true
+}
+
+func (c *memoryCache) Set(key string, resp *CachedResponse) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.items[key]; ok {
+		item.Value.(*memoryCacheItem).resp = resp
+		c.order.MoveToFront(item)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&memoryCacheItem{key: key, resp: resp})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+// cacheKey computes a key for the request. Responses depend on permissions of the user, so the key includes a hash
+// of the user identity rather than the identity itself as it may contain a token
+func cacheKey(req *http.Request, identity string) string {
+	hash := sha256.Sum256([]byte(identity))
+	return req.Method + " " + req.URL.String() + " " + hex.EncodeToString(hash[:8])
+}
+
+// cachedResponse builds a response for the request out of the cached one
+func cachedResponse(req *http.Request, cached *CachedResponse) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
+
+// keepResponse puts a successful response into the cache if it has ETag and its body is small enough. The body of
+// the response is replaced with the one reading from the memory
+func keepResponse(cache Cache, key string, resp *http.Response) error {
+	etag := resp.Header.Get("ETag")
+	if len(etag) == 0 || resp.ContentLength > maxCachedBodySize {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxCachedBodySize {
+		// too large, give it back as is
+		resp.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(body), resp.Body),
+			Closer: resp.Body,
+		}
+		return nil
+	}
+	if err := resp.Body.Close(); err != nil {
+		return err
+	}
+
+	cache.Set(key, &CachedResponse{
+		ETag:   etag,
+		Header: resp.Header.Clone(),
+		Body:   body,
+	})
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -8,24 +8,172 @@ import (
 
 // APIAccess spawns API clients for a given user
 type APIAccess interface {
+	// Client spawns a client authenticated with private token, it is the same as AuthClient(PrivateToken(token))
 	Client(token string) Client
+
+	// AuthClient spawns a client authenticated with the given credentials
+	AuthClient(auth Authenticator) Client
 }
 
-// Client an implementation of gitlab API access for a given user
+// Client an implementation of gitlab API access for a given user. Errors caused by non-successful gitlab responses
+// are *ResponseError, use IsNotFound, IsForbidden, etc to check them out
 type Client interface {
-	// Tags get all tags for a given project
-	Tags(ctx context.Context, project, tagPrefix string) ([]*gitlabdata.Tag, error)
+	// Tags get all tags for a given project. Pages are walked through transparently, optional list options
+	// control page size and the limit of items to retrieve, only the first of them is used.
+	//
+	// Non-empty tagPrefix is treated as an exact tag name for compatibility reasons: a single tag with this name
+	// is returned or an error satisfying IsNotFound if there is no such tag. Use Tag for exact lookups and ListTags
+	// for prefix search
+	Tags(ctx context.Context, project, tagPrefix string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Tag, error)
 
-	// File gets a file with given path and ref (branch, tag or commit SHA) from a given project. Returns os.ErrNotExist
-	// if  gitlab API responses with 404 HTTP status code
+	// File gets a file with given path and ref (branch, tag or commit SHA) from a given project. Returns an error
+	// satisfying IsNotFound and errors.Is(err, os.ErrNotExist) if gitlab API responses with 404 HTTP status code
 	File(ctx context.Context, project, path, ref string) ([]byte, error)
 
+	// FileWithInfo is File also returning file metadata (size, blob id, commit ids, content checksum). Both File and
+	// FileWithInfo verify the content against its SHA-256 checksum and return *IntegrityError on mismatch
+	FileWithInfo(ctx context.Context, project, path, ref string) ([]byte, *gitlabdata.FileInfo, error)
+
+	// FileRaw gets a file with given path and ref as is, without loading it into memory. The caller must close
+	// the returned reader
+	FileRaw(ctx context.Context, project, path, ref string) (io.ReadCloser, error)
+
+	// FileMetadata gets metadata of a file with given path and ref (size, blob id, last commit id, etc) without
+	// downloading its content
+	FileMetadata(ctx context.Context, project, path, ref string) (*gitlabdata.FileInfo, error)
+
+	// Blob gets a blob with the given SHA as is. The caller must close the returned reader
+	Blob(ctx context.Context, project, sha string) (io.ReadCloser, error)
+
+	// BlobMetadata gets a size of a blob with the given SHA without downloading its content. Only Size and BlobID
+	// are set, blobs know nothing about files and commits
+	BlobMetadata(ctx context.Context, project, sha string) (*gitlabdata.FileInfo, error)
+
+	// Tag gets a tag with exactly the given name
+	Tag(ctx context.Context, project, name string) (*gitlabdata.Tag, error)
+
+	// ListTags gets tags for a given project filtered and ordered according to options, which can be nil.
+	// Use ^prefix search to get tags starting with the prefix, an empty list is returned if there are no such tags
+	ListTags(ctx context.Context, project string, opts *gitlabdata.ListTagsOptions) ([]*gitlabdata.Tag, error)
+
+	// ListTagsIter returns an iterator over tags for a given project filtered and ordered according to options
+	ListTagsIter(ctx context.Context, project string, opts *gitlabdata.ListTagsOptions) TagIterator
+
+	// TagsIter returns an iterator over all tags of a given project. Pages are fetched lazily, only when the
+	// previous one was consumed
+	TagsIter(ctx context.Context, project string, opts ...*gitlabdata.ListOptions) TagIterator
+
+	// CreateTag creates a tag with the given name pointing to a given ref (branch, tag or commit SHA). An annotated tag
+	// is created if the message is not empty
+	CreateTag(ctx context.Context, project, name, ref, message string) (*gitlabdata.Tag, error)
+
+	// DeleteTag deletes a tag with the given name
+	DeleteTag(ctx context.Context, project, name string) error
+
+	// Releases gets releases of a given project sorted by release date, newest first
+	Releases(ctx context.Context, project string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Release, error)
+
+	// ReleasesIter returns an iterator over releases of a given project
+	ReleasesIter(ctx context.Context, project string, opts ...*gitlabdata.ListOptions) ReleaseIterator
+
+	// Release gets a release for the given tag
+	Release(ctx context.Context, project, tagName string) (*gitlabdata.Release, error)
+
+	// CreateRelease creates a release. The tag is created as well if it does not exist yet, Ref option is
+	// required then
+	CreateRelease(ctx context.Context, project string, opts *gitlabdata.CreateReleaseOptions) (*gitlabdata.Release, error)
+
+	// UpdateRelease updates a release for the given tag
+	UpdateRelease(ctx context.Context, project, tagName string, opts *gitlabdata.UpdateReleaseOptions) (*gitlabdata.Release, error)
+
+	// DeleteRelease deletes a release for the given tag, the tag itself is kept. Returns the deleted release
+	DeleteRelease(ctx context.Context, project, tagName string) (*gitlabdata.Release, error)
+
+	// Tree lists files and directories in the given path of the repository at the given ref (branch, tag or
+	// commit SHA), empty path and ref stand for the root and the default branch respectively. The whole subtree is
+	// listed if recursive is set. Pages are walked through transparently. Returns an error satisfying IsNotFound
+	// if there is no such path
+	Tree(ctx context.Context, project, path, ref string, recursive bool, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.TreeNode, error)
+
+	// TreeIter returns an iterator over files and directories in the given path of the repository, see Tree for
+	// parameters. Pages are fetched lazily, so huge recursive listings can be consumed without keeping them in memory
+	TreeIter(ctx context.Context, project, path, ref string, recursive bool, opts ...*gitlabdata.ListOptions) TreeNodeIterator
+
 	// ProjectInfo gets an info for a given project
 	ProjectInfo(ctx context.Context, project string) (*gitlabdata.Project, error)
 
-	// Archive gets an archive for a given project. Needs explicit numeric project ID unlike other methods
-	Archive(ctx context.Context, projectID int, ref string) (io.ReadCloser, error)
+	// Archive gets an archive of a given project at the given ref (branch, tag or commit SHA). Optional archive
+	// options choose the format (zip by default) and the subdirectory to archive, only the first of them is used.
+	// The project can be given either by path or by numeric ID, paths are resolved into IDs with ProjectInfo
+	Archive(ctx context.Context, project, ref string, opts ...*gitlabdata.ArchiveOptions) (io.ReadCloser, error)
+
+	// Commits get commits history for given branch, tag or commit (via SHA). Pages are walked through transparently,
+	// optional list options control page size and the limit of items to retrieve, only the first of them is used
+	Commits(ctx context.Context, project string, ref string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Commit, error)
+
+	// CommitsIter returns an iterator over commits history for given branch, tag or commit SHA. Pages are fetched
+	// lazily, so the iteration can be stopped at any time without downloading the rest of the history. Unlike
+	// Commits it does not fall back to the lookup of branches and tags containing the commit
+	CommitsIter(ctx context.Context, project string, ref string, opts ...*gitlabdata.ListOptions) CommitIterator
+
+	// ListCommits gets commits history for given branch, tag or commit SHA filtered and ordered according to options,
+	// which can be nil. Empty ref stands for the default branch
+	ListCommits(ctx context.Context, project, ref string, opts *gitlabdata.CommitsOptions) ([]*gitlabdata.Commit, error)
+
+	// ListCommitsIter returns an iterator over commits history filtered and ordered according to options
+	ListCommitsIter(ctx context.Context, project, ref string, opts *gitlabdata.CommitsOptions) CommitIterator
+
+	// Commit gets a commit with the given SHA (or branch or tag name) including its stats
+	Commit(ctx context.Context, project, sha string) (*gitlabdata.Commit, error)
+
+	// CommitDiff gets per-file diffs of a commit with the given SHA. Pages are walked through transparently
+	CommitDiff(ctx context.Context, project, sha string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Diff, error)
+
+	// CommitDiffIter returns an iterator over per-file diffs of a commit with the given SHA
+	CommitDiffIter(ctx context.Context, project, sha string, opts ...*gitlabdata.ListOptions) DiffIterator
+
+	// Compare gets commits and per-file diffs between two refs (branches, tags or commit SHAs). Changes are
+	// computed from the merge base of from and to unless straight is set, then they are computed from from itself
+	Compare(ctx context.Context, project, from, to string, straight bool) (*gitlabdata.Compare, error)
+
+	// ResolveRef resolves a branch, a tag or a possibly abbreviated commit SHA into a full commit SHA and reports
+	// what kind of ref it was. Tags take precedence over branches with the same name
+	ResolveRef(ctx context.Context, project, ref string) (sha string, kind RefKind, err error)
+
+	// RefsContaining gets branches and tags containing the commit with the given SHA. refType is one of
+	// gitlabdata.CommitRef* values, empty one stands for all of them
+	RefsContaining(ctx context.Context, project, sha, refType string) ([]*gitlabdata.CommitRef, error)
+
+	// RefsContainingIter returns an iterator over branches and tags containing the commit with the given SHA, see
+	// RefsContaining for refType
+	RefsContainingIter(ctx context.Context, project, sha, refType string, opts ...*gitlabdata.ListOptions) CommitRefIterator
+
+	// MergeBase gets the best common ancestor of two or more refs (branches, tags or commit SHAs). Returns an error
+	// satisfying IsNotFound if there is no common ancestor
+	MergeBase(ctx context.Context, project string, refs ...string) (*gitlabdata.Commit, error)
+
+	// IsAncestor checks if ancestor (branch, tag or commit SHA) is an ancestor of descendant using the merge base of
+	// them. A commit is an ancestor of itself. Returns an error satisfying IsNotFound if any of the refs does not
+	// exist
+	IsAncestor(ctx context.Context, project, ancestor, descendant string) (bool, error)
+
+	// Branches gets branches of a given project. Only branches with names containing search are returned if it is
+	// not empty, ^prefix and suffix$ forms are supported as well
+	Branches(ctx context.Context, project, search string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Branch, error)
+
+	// BranchesIter returns an iterator over branches of a given project, see Branches for search parameter
+	BranchesIter(ctx context.Context, project, search string, opts ...*gitlabdata.ListOptions) BranchIterator
+
+	// Branch gets a branch with the given name
+	Branch(ctx context.Context, project, name string) (*gitlabdata.Branch, error)
+
+	// CreateBranch creates a new branch from a given ref (branch, tag or commit SHA)
+	CreateBranch(ctx context.Context, project, name, ref string) (*gitlabdata.Branch, error)
+
+	// DeleteBranch deletes a branch with the given name
+	DeleteBranch(ctx context.Context, project, name string) error
 
-	// Commits get commits history for given branch, tag or commit (via SHA).
-	Commits(ctx context.Context, project string, ref string) ([]*gitlabdata.Commit, error)
+	// DeleteMergedBranches deletes all branches merged into the default one. Protected branches are kept.
+	// Gitlab does the deletion asynchronously
+	DeleteMergedBranches(ctx context.Context, project string) error
 }
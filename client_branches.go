@@ -0,0 +1,120 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func (c apiClient) Branches(ctx context.Context, project, search string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Branch, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "branches").Str("project", project).Str("search", search).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var dest []*gitlabdata.Branch
+	branches := c.BranchesIter(ctx, project, search, opts...)
+	for branches.Next() {
+		dest = append(dest, branches.Value())
+	}
+	if err := branches.Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to get branches")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) BranchesIter(ctx context.Context, project, search string, opts ...*gitlabdata.ListOptions) BranchIterator {
+	logger := c.log(ctx).With().Str("gitlab-request", "branches").Str("project", project).Str("search", search).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var keys map[string]string
+	if len(search) > 0 {
+		keys = map[string]string{"search": search}
+	}
+	urlPath := c.projectURL(project, "repository", "branches")
+	return &branchIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(urlPath, c.auth, keys, listOptions(opts)),
+		},
+	}
+}
+
+func (c apiClient) Branch(ctx context.Context, project, name string) (*gitlabdata.Branch, error) {
+	urlPath := c.projectURL(project, "repository", "branches", url.PathEscape(name))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "branch").Str("project", project).Str("branch", name).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a branch")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Branch
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (c apiClient) CreateBranch(ctx context.Context, project, name, ref string) (*gitlabdata.Branch, error) {
+	urlPath := c.projectURL(project, "repository", "branches")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "create-branch").Str("project", project).Str("branch", name).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodPost, urlPath, c.auth, map[string]string{"branch": name, "ref": ref})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create a branch")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Branch
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (c apiClient) DeleteBranch(ctx context.Context, project, name string) error {
+	urlPath := c.projectURL(project, "repository", "branches", url.PathEscape(name))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "delete-branch").Str("project", project).Str("branch", name).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodDelete, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete a branch")
+		return err
+	}
+	closeBody(ctx, resp)
+
+	return nil
+}
+
+func (c apiClient) DeleteMergedBranches(ctx context.Context, project string) error {
+	urlPath := c.projectURL(project, "repository", "merged_branches")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "delete-merged-branches").Str("project", project).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodDelete, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete merged branches")
+		return err
+	}
+	closeBody(ctx, resp)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func (c apiClient) ListCommits(ctx context.Context, project, ref string, opts *gitlabdata.CommitsOptions) ([]*gitlabdata.Commit, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "commits").Str("project", project).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var dest []*gitlabdata.Commit
+	commits := c.ListCommitsIter(ctx, project, ref, opts)
+	for commits.Next() {
+		dest = append(dest, commits.Value())
+	}
+	if err := commits.Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to get commits")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) ListCommitsIter(ctx context.Context, project, ref string, opts *gitlabdata.CommitsOptions) CommitIterator {
+	if opts == nil {
+		opts = &gitlabdata.CommitsOptions{}
+	}
+
+	logger := c.log(ctx).With().Str("gitlab-request", "commits").Str("project", project).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	keys := map[string]string{}
+	if len(ref) > 0 {
+		keys["ref_name"] = ref
+	}
+	setOption(keys, "path", opts.Path)
+	setTimeOption(keys, "since", opts.Since)
+	setTimeOption(keys, "until", opts.Until)
+	setOption(keys, "author", opts.Author)
+	setBoolOption(keys, "first_parent", opts.FirstParent)
+	setBoolOption(keys, "with_stats", opts.WithStats)
+	setBoolOption(keys, "all", opts.All)
+	setOption(keys, "order", opts.Order)
+	return &commitIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(c.projectURL(project, "repository", "commits"), c.auth, keys, &opts.ListOptions),
+		},
+	}
+}
+
+func (c apiClient) Commit(ctx context.Context, project, sha string) (*gitlabdata.Commit, error) {
+	urlPath := c.projectURL(project, "repository", "commits", url.PathEscape(sha))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "commit").Str("project", project).Str("sha", sha).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, map[string]string{"stats": "true"})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a commit")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Commit
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (c apiClient) CommitDiff(ctx context.Context, project, sha string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Diff, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "commit-diff").Str("project", project).Str("sha", sha).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var dest []*gitlabdata.Diff
+	diffs := c.CommitDiffIter(ctx, project, sha, opts...)
+	for diffs.Next() {
+		dest = append(dest, diffs.Value())
+	}
+	if err := diffs.Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to get a commit diff")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) CommitDiffIter(ctx context.Context, project, sha string, opts ...*gitlabdata.ListOptions) DiffIterator {
+	logger := c.log(ctx).With().Str("gitlab-request", "commit-diff").Str("project", project).Str("sha", sha).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	urlPath := c.projectURL(project, "repository", "commits", url.PathEscape(sha), "diff")
+	return &diffIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(urlPath, c.auth, nil, listOptions(opts)),
+		},
+	}
+}
+
+func (c apiClient) Compare(ctx context.Context, project, from, to string, straight bool) (*gitlabdata.Compare, error) {
+	urlPath := c.projectURL(project, "repository", "compare")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "compare").Str("project", project).
+		Str("from", from).Str("to", to).Bool("straight", straight).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	keys := map[string]string{
+		"from":     from,
+		"to":       to,
+		"straight": strconv.FormatBool(straight),
+	}
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, keys)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to compare refs")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Compare
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
@@ -0,0 +1,183 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func (c apiClient) FileWithInfo(ctx context.Context, project, path, ref string) ([]byte, *gitlabdata.FileInfo, error) {
+	urlPath := c.projectURL(project, "repository", "files", url.PathEscape(path))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "file").Str("project", project).Str("file", path).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, map[string]string{"ref": ref})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a file")
+		return nil, nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest struct {
+		gitlabdata.FileInfo
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, nil, err
+	}
+
+	var content []byte
+	switch dest.Encoding {
+	case "base64":
+		content, err = base64.StdEncoding.DecodeString(dest.Content)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode file content")
+			return nil, nil, err
+		}
+	case "text", "":
+		content = []byte(dest.Content)
+	default:
+		err := fmt.Errorf("encoding %s is not supported", dest.Encoding)
+		logger.Error().Err(err).Msg("failed to decode file content")
+		return nil, nil, err
+	}
+
+	if len(dest.ContentSHA256) > 0 {
+		sum := sha256.Sum256(content)
+		if actual := hex.EncodeToString(sum[:]); actual != dest.ContentSHA256 {
+			err := &IntegrityError{
+				Path:     path,
+				Ref:      ref,
+				Expected: dest.ContentSHA256,
+				Actual:   actual,
+			}
+			logger.Error().Err(err).Msg("file content is corrupted")
+			return nil, nil, err
+		}
+	}
+
+	return content, &dest.FileInfo, nil
+}
+
+func (c apiClient) FileRaw(ctx context.Context, project, path, ref string) (io.ReadCloser, error) {
+	urlPath := c.projectURL(project, "repository", "files", url.PathEscape(path), "raw")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "file-raw").Str("project", project).Str("file", path).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, map[string]string{"ref": ref})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a raw file")
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (c apiClient) FileMetadata(ctx context.Context, project, path, ref string) (*gitlabdata.FileInfo, error) {
+	urlPath := c.projectURL(project, "repository", "files", url.PathEscape(path))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "file-metadata").Str("project", project).Str("file", path).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodHead, urlPath, c.auth, map[string]string{"ref": ref})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get file metadata")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	dest, err := fileInfoFromHeader(resp.Header)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to parse file metadata")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) Blob(ctx context.Context, project, sha string) (io.ReadCloser, error) {
+	urlPath := c.projectURL(project, "repository", "blobs", url.PathEscape(sha), "raw")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "blob").Str("project", project).Str("sha", sha).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a blob")
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (c apiClient) BlobMetadata(ctx context.Context, project, sha string) (*gitlabdata.FileInfo, error) {
+	urlPath := c.projectURL(project, "repository", "blobs", url.PathEscape(sha), "raw")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "blob-metadata").Str("project", project).Str("sha", sha).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodHead, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get blob metadata")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	if resp.ContentLength < 0 {
+		err := fmt.Errorf("blob size is not known")
+		logger.Error().Err(err).Msg("failed to get blob metadata")
+		return nil, err
+	}
+
+	return &gitlabdata.FileInfo{
+		Size:   resp.ContentLength,
+		BlobID: sha,
+	}, nil
+}
+
+// fileInfoFromHeader collects file metadata out of X-Gitlab-* headers of a file response
+func fileInfoFromHeader(header http.Header) (*gitlabdata.FileInfo, error) {
+	dest := &gitlabdata.FileInfo{
+		FileName:      header.Get("X-Gitlab-File-Name"),
+		FilePath:      header.Get("X-Gitlab-File-Path"),
+		Encoding:      header.Get("X-Gitlab-Encoding"),
+		ContentSHA256: header.Get("X-Gitlab-Content-Sha256"),
+		Ref:           header.Get("X-Gitlab-Ref"),
+		BlobID:        header.Get("X-Gitlab-Blob-Id"),
+		CommitID:      header.Get("X-Gitlab-Commit-Id"),
+		LastCommitID:  header.Get("X-Gitlab-Last-Commit-Id"),
+	}
+	if len(dest.FileName) == 0 && len(dest.FilePath) > 0 {
+		dest.FileName = path.Base(dest.FilePath)
+	}
+
+	if value := header.Get("X-Gitlab-Size"); len(value) > 0 {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file size %s: %w", value, err)
+		}
+		dest.Size = size
+	}
+	if value := header.Get("X-Gitlab-Execute-Filemode"); len(value) > 0 {
+		executable, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid execute filemode flag %s: %w", value, err)
+		}
+		dest.ExecuteFilemode = executable
+	}
+
+	return dest, nil
+}
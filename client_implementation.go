@@ -1,51 +1,132 @@
 package gitlab
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 
 	"github.com/sirkon/gitlab/gitlabdata"
 )
 
+// New creates an access point to gitlab API instance with the given base URL. The URL is validated and normalised:
+// https scheme is used when it is omitted and /api/v4 path is used when there is no path at all, so both
+// gitlab.com/api/v4 and https://gitlab.com turn into https://gitlab.com/api/v4
+func New(baseURL string, opts ...Option) (APIAccess, error) {
+	normalized, err := normalizeURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u, _ := url.Parse(normalized); len(u.Path) == 0 {
+		normalized += "/api/v4"
+	}
+
+	return newAPIAccess(normalized, opts...), nil
+}
+
 // NewAPIAccess creates an access point to gitlab API instance
 //   httpClient can be nil, http.DefaultClient will be used if it is
 //   url must be a full path to gitlab API, e.g. https://gitlab.com/api/v4, etc
-func NewAPIAccess(httpClient *http.Client, url string) APIAccess {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
+//   opts are optional settings, such as retry policy or rate limiter
+// It is a compatibility wrapper over New, the URL is used as is if it cannot be normalised
+func NewAPIAccess(httpClient *http.Client, url string, opts ...Option) APIAccess {
+	if normalized, err := normalizeURL(url); err == nil {
+		url = normalized
 	}
-	return &apiAccess{
-		client: httpClient,
+
+	return newAPIAccess(url, append([]Option{WithHTTPClient(httpClient)}, opts...)...)
+}
+
+func newAPIAccess(url string, opts ...Option) *apiAccess {
+	res := &apiAccess{
+		client: http.DefaultClient,
 		url:    url,
 	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// normalizeURL validates gitlab API base URL and brings it to the canonical form: with a scheme and without
+// trailing slashes
+func normalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "", errors.New("gitlab API URL must not be empty")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid gitlab API URL %s: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid gitlab API URL %s: unsupported scheme %s", raw, u.Scheme)
+	}
+	if len(u.Host) == 0 {
+		return "", fmt.Errorf("invalid gitlab API URL %s: host is missing", raw)
+	}
+	if len(u.RawQuery) > 0 || len(u.Fragment) > 0 {
+		return "", fmt.Errorf("invalid gitlab API URL %s: query and fragment are not allowed", raw)
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
 }
 
 type apiAccess struct {
-	client *http.Client
-	url    string
+	client        *http.Client
+	url           string
+	userAgent     string
+	headers       http.Header
+	logger        *zerolog.Logger
+	retry         *RetryPolicy
+	limiter       RateLimiter
+	cache         Cache
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+}
+
+// withLogger attaches the default logger to the context if it has no logger of its own
+func (a *apiAccess) withLogger(ctx context.Context) context.Context {
+	if a.logger == nil || zerolog.Ctx(ctx) != zerolog.Ctx(context.Background()) {
+		return ctx
+	}
+	return a.logger.WithContext(ctx)
 }
 
 func (a *apiAccess) Client(token string) Client {
+	return a.AuthClient(PrivateToken(token))
+}
+
+func (a *apiAccess) AuthClient(auth Authenticator) Client {
 	return apiClient{
-		token:  token,
+		auth:   auth,
 		access: a,
 	}
 }
 
-func (a *apiAccess) makeRequest(ctx context.Context, project, token string, keys map[string]string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, a.url+project, nil)
+func (a *apiAccess) makeRequest(ctx context.Context, project string, auth Authenticator, keys map[string]string) (*http.Response, error) {
+	return a.makeMethodRequest(ctx, http.MethodGet, project, auth, keys)
+}
+
+// makeMethodRequest makes a request with the given HTTP method, keys are passed as query parameters
+func (a *apiAccess) makeMethodRequest(ctx context.Context, method, project string, auth Authenticator, keys map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, a.url+project, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create req to gitlab API: %s", err)
 	}
@@ -55,15 +136,140 @@ func (a *apiAccess) makeRequest(ctx context.Context, project, token string, keys
 		q.Set(key, value)
 	}
 	req.URL.RawQuery = q.Encode()
-	req.Header.Set("PRIVATE-TOKEN", token)
-	req = req.WithContext(ctx)
 
+	return a.send(ctx, req, auth)
+}
+
+// makeQueryRequest makes GET request with the given query, it is needed for parameters with multiple values
+func (a *apiAccess) makeQueryRequest(ctx context.Context, project string, auth Authenticator, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, a.url+project, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create req to gitlab API: %s", err)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	return a.send(ctx, req, auth)
+}
+
+// makeJSONRequest makes a request with the given HTTP method and JSON encoded body
+func (a *apiAccess) makeJSONRequest(ctx context.Context, method, project string, auth Authenticator, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request body: %w", err)
+	}
+
+	req, err := http.NewRequest(method, a.url+project, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create req to gitlab API: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return a.send(ctx, req, auth)
+}
+
+// send sends a prepared request to gitlab API on behalf of the user with the given credentials. Failed requests are retried
+// according to the retry policy
+func (a *apiAccess) send(ctx context.Context, req *http.Request, auth Authenticator) (*http.Response, error) {
+	ctx = a.withLogger(ctx)
+	for key, values := range a.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if len(a.userAgent) > 0 {
+		req.Header.Set("User-Agent", a.userAgent)
+	}
+
+	attempts := a.retry.attempts()
+	if attempts > 1 && (!a.retry.methodAllowed(req.Method) || (req.Body != nil && req.GetBody == nil)) {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.WithContext(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := a.sendOnce(ctx, attemptReq, auth)
+		if err == nil || attempt >= attempts {
+			return resp, err
+		}
+
+		var status int
+		var header http.Header
+		var respErr *ResponseError
+		switch {
+		case errors.As(err, &respErr):
+			if !a.retry.statusAllowed(respErr.StatusCode) {
+				return nil, err
+			}
+			status = respErr.StatusCode
+			header = respErr.Header
+		case ctx.Err() != nil:
+			return nil, err
+		case !a.retry.errorAllowed(err):
+			return nil, err
+		}
+
+		delay, ok := a.retry.delay(attempt, status, header)
+		if !ok {
+			zerolog.Ctx(ctx).Warn().Err(err).Dur("delay", delay).Msg("gitlab requested too long delay, giving up")
+			return nil, err
+		}
+		zerolog.Ctx(ctx).Warn().Err(err).Int("attempt", attempt).Dur("delay", delay).Msg("retrying gitlab request")
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sendOnce makes a single attempt to send a request
+func (a *apiAccess) sendOnce(ctx context.Context, req *http.Request, auth Authenticator) (*http.Response, error) {
+	identity := auth.Identity()
+	if a.limiter != nil {
+		if err := a.limiter.Wait(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+	if err := auth.Authenticate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	var key string
+	var cached *CachedResponse
+	if a.cache != nil && req.Method == http.MethodGet {
+		key = cacheKey(req, identity)
+		if item, ok := a.cache.Get(key); ok {
+			cached = item
+			req.Header.Set("If-None-Match", item.ETag)
+		}
+	}
+
+	for _, hook := range a.requestHooks {
+		hook(req)
+	}
 	zerolog.Ctx(ctx).Debug().Str("gitlab-url", req.URL.RawPath).Msg("gitlab remote request")
 	resp, err := a.client.Do(req)
+	for _, hook := range a.responseHooks {
+		hook(req, resp, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get a response: %s", err)
+		return nil, fmt.Errorf("failed to get a response: %w", err)
+	}
+	if a.limiter != nil {
+		a.limiter.Update(identity, resp.Header)
 	}
-	if resp.StatusCode != http.StatusOK {
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		closeBody(ctx, resp)
+		zerolog.Ctx(ctx).Debug().Msg("gitlab response was not modified, using the cached one")
+		return cachedResponse(req, cached), nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		defer closeBody(ctx, resp)
 		res, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
@@ -71,23 +277,30 @@ func (a *apiAccess) makeRequest(ctx context.Context, project, token string, keys
 			return nil, err
 		}
 		zerolog.Ctx(ctx).Error().Int("error-code", resp.StatusCode).Str("error-response", string(res)).Msg("gitlab error")
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, os.ErrNotExist
-		}
-		if len(res) == 0 {
-			return nil, errors.New("gitlab error")
+		return nil, newResponseError(resp, res)
+	}
+
+	if len(key) > 0 {
+		if err := keepResponse(a.cache, key, resp); err != nil {
+			closeBody(ctx, resp)
+			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to read out response content")
+			return nil, err
 		}
-		return nil, errors.Errorf("gitlab error: %s", string(res))
 	}
 
 	return resp, nil
 }
 
 type apiClient struct {
-	token  string
+	auth   Authenticator
 	access *apiAccess
 }
 
+// log returns a logger attached to the context or the default one of API access
+func (c apiClient) log(ctx context.Context) *zerolog.Logger {
+	return zerolog.Ctx(c.access.withLogger(ctx))
+}
+
 func closeBody(ctx context.Context, resp *http.Response) {
 	if err := resp.Body.Close(); err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to close response body")
@@ -103,91 +316,43 @@ func (c apiClient) projectURL(project string, items ...string) string {
 	return strings.Join(urlItems, "/")
 }
 
-func (c apiClient) Tags(ctx context.Context, project, tagPrefix string) ([]*gitlabdata.Tag, error) {
-	var urlPath string
+func (c apiClient) Tags(ctx context.Context, project, tagPrefix string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Tag, error) {
 	if len(tagPrefix) > 0 {
-		urlPath = c.projectURL(project, "repository", "tags", tagPrefix)
-	} else {
-		urlPath = c.projectURL(project, "repository", "tags")
+		tag, err := c.Tag(ctx, project, tagPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return []*gitlabdata.Tag{tag}, nil
 	}
 
-	logger := zerolog.Ctx(ctx).With().Str("gitlab-request", "tags").Str("project", project).Str("tag-prefix", tagPrefix).Logger()
+	logger := c.log(ctx).With().Str("gitlab-request", "tags").Str("project", project).Logger()
 	ctx = (&logger).WithContext(ctx)
 
-	resp, err := c.access.makeRequest(ctx, urlPath, c.token, nil)
-	if err != nil {
+	var dest []*gitlabdata.Tag
+	tags := c.TagsIter(ctx, project, opts...)
+	for tags.Next() {
+		dest = append(dest, tags.Value())
+	}
+	if err := tags.Err(); err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to get requested tags")
 		return nil, err
 	}
-	defer closeBody(ctx, resp)
-
-	var dest []*gitlabdata.Tag
-	unmarshaler := json.NewDecoder(resp.Body)
-	if len(tagPrefix) > 0 {
-		var tag gitlabdata.Tag
-		if err := unmarshaler.Decode(&tag); err != nil {
-			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to unmarshal a response")
-			return nil, err
-		}
-		dest = append(dest, &tag)
-	} else {
-		if err := unmarshaler.Decode(&dest); err != nil {
-			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to unmarshal a response")
-			return nil, err
-		}
-	}
 
 	return dest, nil
 }
 
 func (c apiClient) File(ctx context.Context, project, path, ref string) ([]byte, error) {
-	urlPath := c.projectURL(project, "repository", "files", url.PathEscape(path))
-
-	logger := zerolog.Ctx(ctx).With().Str("gitlab-request", "file").Str("project", project).Str("file", path).Logger()
-	ctx = (&logger).WithContext(ctx)
-
-	resp, err := c.access.makeRequest(ctx, urlPath, c.token, map[string]string{"ref": ref})
-	if err != nil {
-		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to get a file")
-		return nil, err
-	}
-	defer closeBody(ctx, resp)
-
-	type responseType struct {
-		Encoding      string `json:"encoding"`
-		Content       string `json:"content"`
-		ContentSHA256 string `json:"content_sha256"`
-	}
-	dec := json.NewDecoder(resp.Body)
-	var dest responseType
-
-	if err := dec.Decode(&dest); err != nil {
-		return nil, err
-	}
-
-	var content []byte
-	switch dest.Encoding {
-	case "base64":
-		content, err = base64.StdEncoding.DecodeString(dest.Content)
-		if err != nil {
-			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to decode file content")
-			return nil, err
-		}
-	default:
-		zerolog.Ctx(ctx).Error().Msgf("encoding %s is not supported", dest.Encoding)
-		return nil, fmt.Errorf("encoding %s is not supported", dest.Encoding)
-	}
-
-	return content, nil
+	content, _, err := c.FileWithInfo(ctx, project, path, ref)
+	return content, err
 }
 
 func (c apiClient) ProjectInfo(ctx context.Context, project string) (*gitlabdata.Project, error) {
 	urlPath := c.projectURL(project)
 
-	logger := zerolog.Ctx(ctx).With().Str("gitlab-request", "project-info").Str("project", project).Logger()
+	logger := c.log(ctx).With().Str("gitlab-request", "project-info").Str("project", project).Logger()
 	ctx = (&logger).WithContext(ctx)
 
-	resp, err := c.access.makeRequest(ctx, urlPath, c.token, nil)
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, nil)
 	if err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to get project info")
 		return nil, err
@@ -204,73 +369,177 @@ func (c apiClient) ProjectInfo(ctx context.Context, project string) (*gitlabdata
 	return &dest, nil
 }
 
-func (c apiClient) Archive(ctx context.Context, projectID int, tag string) (io.ReadCloser, error) {
-	urlPath := c.projectURL(strconv.Itoa(projectID), "repository", "archive.zip")
+func (c apiClient) Archive(ctx context.Context, project, ref string, opts ...*gitlabdata.ArchiveOptions) (io.ReadCloser, error) {
+	format := gitlabdata.ArchiveFormatZip
+	keys := map[string]string{"sha": ref}
+	if len(opts) > 0 && opts[0] != nil {
+		if opts[0].Format != nil {
+			format = *opts[0].Format
+		}
+		setOption(keys, "path", opts[0].Path)
+	}
+	switch format {
+	case gitlabdata.ArchiveFormatTarGz, gitlabdata.ArchiveFormatTarBz2, gitlabdata.ArchiveFormatTar, gitlabdata.ArchiveFormatZip:
+	default:
+		return nil, fmt.Errorf("unsupported archive format %s", format)
+	}
 
-	logger := zerolog.Ctx(ctx).With().Str("gitlab-request", "archive").Int("project-id", projectID).Str("tag", tag).Logger()
+	logger := c.log(ctx).With().Str("gitlab-request", "archive").Str("project", project).Str("ref", ref).Str("format", format).Logger()
 	ctx = (&logger).WithContext(ctx)
 
-	resp, err := c.access.makeRequest(ctx, urlPath, c.token, map[string]string{"sha": tag})
+	// archives are only served reliably with numeric project IDs, encoded project paths clash with file extensions
+	if _, err := strconv.Atoi(project); err != nil {
+		info, err := c.ProjectInfo(ctx, project)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get project ID for an archive")
+			return nil, err
+		}
+		project = strconv.Itoa(info.ID)
+	}
+	urlPath := c.projectURL(project, "repository", "archive."+format)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, keys)
 	if err != nil {
-		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to get an archive")
+		logger.Error().Err(err).Msg("failed to get an archive")
 		return nil, err
 	}
 
 	return resp.Body, nil
 }
 
-type referenceItem struct {
-	Type string `json:"type"`
-	Name string `json:"name"`
+// listOptions picks list options out of optional arguments
+func listOptions(opts []*gitlabdata.ListOptions) *gitlabdata.ListOptions {
+	if len(opts) == 0 {
+		return nil
+	}
+	return opts[0]
+}
+
+// setOption puts an optional value into request keys if it is set
+func setOption(keys map[string]string, key string, value *string) {
+	if value != nil {
+		keys[key] = *value
+	}
+}
+
+// setBoolOption puts an optional boolean value into request keys if it is set
+func setBoolOption(keys map[string]string, key string, value *bool) {
+	if value != nil {
+		keys[key] = strconv.FormatBool(*value)
+	}
 }
 
-func (c apiClient) Commits(ctx context.Context, project string, ref string) ([]*gitlabdata.Commit, error) {
-	urlPath := c.projectURL(project, "repository", "commits")
+// setTimeOption puts an optional time value into request keys in ISO 8601 format if it is set
+func setTimeOption(keys map[string]string, key string, value *time.Time) {
+	if value != nil {
+		keys[key] = value.Format(time.RFC3339)
+	}
+}
 
-	logger := zerolog.Ctx(ctx).With().Str("gitlab-request", "commits").Str("project", project).Logger()
+// decodePage requests the next page and decodes its content into dest. Returns false if there are no pages left
+func decodePage(ctx context.Context, p *pager, dest interface{}) (bool, error) {
+	resp, err := p.nextPage(ctx)
+	if err != nil {
+		return false, err
+	}
+	if resp == nil {
+		return false, nil
+	}
+	defer closeBody(ctx, resp)
+
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c apiClient) Commits(ctx context.Context, project string, ref string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Commit, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "commits").Str("project", project).Str("ref", ref).Logger()
 	ctx = (&logger).WithContext(ctx)
 
-	var dest []*gitlabdata.Commit
+	// ref_name accepts branches, tags and commit SHAs alike
+	dest, err := c.collectCommits(ctx, project, ref, opts...)
+	if err == nil && len(dest) > 0 {
+		return dest, nil
+	}
+	if err != nil && !IsNotFound(err) {
+		logger.Error().Err(err).Msg("failed to get commits")
+		return nil, err
+	}
 
-	resp, err := c.access.makeRequest(ctx, urlPath, c.token, map[string]string{"ref_name": ref, "per_page": "20000"})
-	if err == nil {
-		defer closeBody(ctx, resp)
-		unmarshaler := json.NewDecoder(resp.Body)
-		if err := unmarshaler.Decode(&dest); err != nil {
-			logger.Error().Err(err).Msg("failed to unmarshal commits response")
+	// abbreviated SHAs are not accepted by some gitlab versions, try the full one
+	commit, commitErr := c.Commit(ctx, project, ref)
+	if commitErr != nil {
+		if err == nil && IsNotFound(commitErr) {
+			// there is just nothing in the history
+			return dest, nil
+		}
+		logger.Error().Err(commitErr).Msg("failed to get commits")
+		return nil, commitErr
+	}
+	if commit.ID != ref {
+		dest, err = c.collectCommits(ctx, project, commit.ID, opts...)
+		if err == nil && len(dest) > 0 {
+			return dest, nil
+		}
+		if err != nil && !IsNotFound(err) {
+			logger.Error().Err(err).Msg("failed to get commits")
 			return nil, err
 		}
-		return dest, nil
 	}
 
-	logger.Warn().Err(err).Msg("failed to get an archive via branch or tag name, trying to get it via commit SHA")
-	referenceURLPath := c.projectURL(project, "repository", "commits", ref, "refs")
-	resp, err = c.access.makeRequest(ctx, referenceURLPath, c.token, nil)
-	if err != nil {
-		logger.Error().Err(err).Msgf("failed to get references for a given commit `%s`", ref)
+	logger.Warn().Msg("failed to get commits via ref name, trying refs containing the commit as a last resort")
+	return c.commitsViaRefs(ctx, project, commit.ID, listOptions(opts))
+}
+
+// collectCommits collects commits history of the ref
+func (c apiClient) collectCommits(ctx context.Context, project, ref string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Commit, error) {
+	var dest []*gitlabdata.Commit
+	commits := c.CommitsIter(ctx, project, ref, opts...)
+	for commits.Next() {
+		dest = append(dest, commits.Value())
+	}
+	if err := commits.Err(); err != nil {
 		return nil, err
 	}
-	defer closeBody(ctx, resp)
-	var references []referenceItem
-	unmarshaler := json.NewDecoder(resp.Body)
-	if err := unmarshaler.Decode(&references); err != nil {
-		logger.Error().Err(err).Msg("failed to unmarshal commit references response")
+	return dest, nil
+}
+
+// commitsViaRefs looks for the commit in histories of refs containing it and collects commits starting from it.
+// Histories are walked lazily and only until the commit is found
+func (c apiClient) commitsViaRefs(ctx context.Context, project, sha string, opts *gitlabdata.ListOptions) ([]*gitlabdata.Commit, error) {
+	logger := zerolog.Ctx(ctx)
+
+	references, err := c.RefsContaining(ctx, project, sha, gitlabdata.CommitRefAll)
+	if err != nil {
+		logger.Error().Err(err).Msgf("failed to get references for a given commit `%s`", sha)
 		return nil, err
 	}
 
+	limit := 0
+	var pageOpts *gitlabdata.ListOptions
+	if opts != nil {
+		limit = opts.MaxItems
+		pageOpts = &gitlabdata.ListOptions{PerPage: opts.PerPage}
+	}
+
 	// got commit references, trying them out
 	for _, repoRef := range references {
-		dest, err = c.Commits(ctx, project, repoRef.Name)
-		if err != nil {
+		var dest []*gitlabdata.Commit
+		commits := c.CommitsIter(ctx, project, repoRef.Name, pageOpts)
+		for commits.Next() && (limit <= 0 || len(dest) < limit) {
+			commit := commits.Value()
+			if len(dest) == 0 && commit.ID != sha {
+				continue
+			}
+			dest = append(dest, commit)
+		}
+		if err := commits.Err(); err != nil {
 			logger.Error().Err(err).Msgf("failed to retrieve commits of %s %s", repoRef.Type, repoRef.Name)
 			continue
 		}
-
-		// got commits, filter every commit happened after the given one and return the result
-		for i, commit := range dest {
-			if commit.ShortID == ref || commit.ID == ref {
-				return dest[i:], nil
-			}
+		if len(dest) > 0 {
+			return dest, nil
 		}
 	}
 
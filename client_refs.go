@@ -0,0 +1,151 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// RefKind is a kind of a ref resolved with ResolveRef
+type RefKind string
+
+// List of ref kinds
+const (
+	RefKindBranch RefKind = "branch"
+	RefKindTag    RefKind = "tag"
+	RefKindCommit RefKind = "commit"
+)
+
+func (c apiClient) ResolveRef(ctx context.Context, project, ref string) (string, RefKind, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "resolve-ref").Str("project", project).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	commit, err := c.Commit(ctx, project, ref)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to resolve a ref")
+		return "", "", err
+	}
+
+	// a branch or a tag named after the ref contains the commit it points to
+	refs, err := c.RefsContaining(ctx, project, commit.ID, gitlabdata.CommitRefAll)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get refs of a commit")
+		return "", "", err
+	}
+	kind := RefKindCommit
+	for _, item := range refs {
+		if item.Name != ref {
+			continue
+		}
+		switch item.Type {
+		case gitlabdata.CommitRefTag:
+			// tags take precedence over branches the same way they do in git
+			return commit.ID, RefKindTag, nil
+		case gitlabdata.CommitRefBranch:
+			kind = RefKindBranch
+		}
+	}
+
+	return commit.ID, kind, nil
+}
+
+func (c apiClient) RefsContaining(ctx context.Context, project, sha, refType string) ([]*gitlabdata.CommitRef, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "commit-refs").Str("project", project).
+		Str("sha", sha).Str("type", refType).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var dest []*gitlabdata.CommitRef
+	refs := c.RefsContainingIter(ctx, project, sha, refType)
+	for refs.Next() {
+		dest = append(dest, refs.Value())
+	}
+	if err := refs.Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to get refs of a commit")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) RefsContainingIter(ctx context.Context, project, sha, refType string, opts ...*gitlabdata.ListOptions) CommitRefIterator {
+	logger := c.log(ctx).With().Str("gitlab-request", "commit-refs").Str("project", project).
+		Str("sha", sha).Str("type", refType).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var keys map[string]string
+	if len(refType) > 0 {
+		keys = map[string]string{"type": refType}
+	}
+	urlPath := c.projectURL(project, "repository", "commits", url.PathEscape(sha), "refs")
+	return &commitRefIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(urlPath, c.auth, keys, listOptions(opts)),
+		},
+	}
+}
+
+func (c apiClient) MergeBase(ctx context.Context, project string, refs ...string) (*gitlabdata.Commit, error) {
+	urlPath := c.projectURL(project, "repository", "merge_base")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "merge-base").Str("project", project).Strs("refs", refs).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	query := url.Values{}
+	for _, ref := range refs {
+		query.Add("refs[]", ref)
+	}
+	resp, err := c.access.makeQueryRequest(ctx, urlPath, c.auth, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a merge base")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Commit
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (c apiClient) IsAncestor(ctx context.Context, project, ancestor, descendant string) (bool, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "is-ancestor").Str("project", project).
+		Str("ancestor", ancestor).Str("descendant", descendant).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	commit, err := c.Commit(ctx, project, ancestor)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to resolve ancestor")
+		return false, err
+	}
+	head, err := c.Commit(ctx, project, descendant)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to resolve descendant")
+		return false, err
+	}
+
+	return commitIsAncestor(ctx, c, project, commit.ID, head.ID)
+}
+
+// commitIsAncestor checks if the commit is an ancestor of the head using their merge base. Both commits must be
+// resolved into existing ones, so the lack of merge base is the only reason for gitlab to respond with 404
+func commitIsAncestor(ctx context.Context, client Client, project, commit, head string) (bool, error) {
+	if commit == head {
+		return true, nil
+	}
+
+	base, err := client.MergeBase(ctx, project, commit, head)
+	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return base.ID == commit, nil
+}
@@ -0,0 +1,114 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func (c apiClient) Releases(ctx context.Context, project string, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.Release, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "releases").Str("project", project).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var dest []*gitlabdata.Release
+	releases := c.ReleasesIter(ctx, project, opts...)
+	for releases.Next() {
+		dest = append(dest, releases.Value())
+	}
+	if err := releases.Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to get releases")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) ReleasesIter(ctx context.Context, project string, opts ...*gitlabdata.ListOptions) ReleaseIterator {
+	logger := c.log(ctx).With().Str("gitlab-request", "releases").Str("project", project).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	return &releaseIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(c.projectURL(project, "releases"), c.auth, nil, listOptions(opts)),
+		},
+	}
+}
+
+func (c apiClient) Release(ctx context.Context, project, tagName string) (*gitlabdata.Release, error) {
+	urlPath := c.projectURL(project, "releases", url.PathEscape(tagName))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "release").Str("project", project).Str("tag", tagName).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a release")
+		return nil, err
+	}
+
+	return c.decodeRelease(ctx, resp)
+}
+
+func (c apiClient) CreateRelease(ctx context.Context, project string, opts *gitlabdata.CreateReleaseOptions) (*gitlabdata.Release, error) {
+	urlPath := c.projectURL(project, "releases")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "create-release").Str("project", project).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeJSONRequest(ctx, http.MethodPost, urlPath, c.auth, opts)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create a release")
+		return nil, err
+	}
+
+	return c.decodeRelease(ctx, resp)
+}
+
+func (c apiClient) UpdateRelease(ctx context.Context, project, tagName string, opts *gitlabdata.UpdateReleaseOptions) (*gitlabdata.Release, error) {
+	urlPath := c.projectURL(project, "releases", url.PathEscape(tagName))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "update-release").Str("project", project).Str("tag", tagName).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeJSONRequest(ctx, http.MethodPut, urlPath, c.auth, opts)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update a release")
+		return nil, err
+	}
+
+	return c.decodeRelease(ctx, resp)
+}
+
+func (c apiClient) DeleteRelease(ctx context.Context, project, tagName string) (*gitlabdata.Release, error) {
+	urlPath := c.projectURL(project, "releases", url.PathEscape(tagName))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "delete-release").Str("project", project).Str("tag", tagName).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodDelete, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete a release")
+		return nil, err
+	}
+
+	return c.decodeRelease(ctx, resp)
+}
+
+// decodeRelease decodes a release out of the response and closes its body
+func (c apiClient) decodeRelease(ctx context.Context, resp *http.Response) (*gitlabdata.Release, error) {
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Release
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
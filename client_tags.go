@@ -0,0 +1,111 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func (c apiClient) Tag(ctx context.Context, project, name string) (*gitlabdata.Tag, error) {
+	urlPath := c.projectURL(project, "repository", "tags", url.PathEscape(name))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "tag").Str("project", project).Str("tag", name).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeRequest(ctx, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get a tag")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Tag
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (c apiClient) ListTags(ctx context.Context, project string, opts *gitlabdata.ListTagsOptions) ([]*gitlabdata.Tag, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "tags").Str("project", project).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var dest []*gitlabdata.Tag
+	tags := c.ListTagsIter(ctx, project, opts)
+	for tags.Next() {
+		dest = append(dest, tags.Value())
+	}
+	if err := tags.Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to get tags")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) ListTagsIter(ctx context.Context, project string, opts *gitlabdata.ListTagsOptions) TagIterator {
+	if opts == nil {
+		opts = &gitlabdata.ListTagsOptions{}
+	}
+
+	logger := c.log(ctx).With().Str("gitlab-request", "tags").Str("project", project).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	keys := map[string]string{}
+	setOption(keys, "search", opts.Search)
+	setOption(keys, "order_by", opts.OrderBy)
+	setOption(keys, "sort", opts.Sort)
+	return &tagIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(c.projectURL(project, "repository", "tags"), c.auth, keys, &opts.ListOptions),
+		},
+	}
+}
+
+func (c apiClient) CreateTag(ctx context.Context, project, name, ref, message string) (*gitlabdata.Tag, error) {
+	urlPath := c.projectURL(project, "repository", "tags")
+
+	logger := c.log(ctx).With().Str("gitlab-request", "create-tag").Str("project", project).Str("tag", name).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	keys := map[string]string{"tag_name": name, "ref": ref}
+	if len(message) > 0 {
+		keys["message"] = message
+	}
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodPost, urlPath, c.auth, keys)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create a tag")
+		return nil, err
+	}
+	defer closeBody(ctx, resp)
+
+	var dest gitlabdata.Tag
+	if err := json.NewDecoder(resp.Body).Decode(&dest); err != nil {
+		logger.Error().Err(err).Msg("failed to unmarshal a response")
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (c apiClient) DeleteTag(ctx context.Context, project, name string) error {
+	urlPath := c.projectURL(project, "repository", "tags", url.PathEscape(name))
+
+	logger := c.log(ctx).With().Str("gitlab-request", "delete-tag").Str("project", project).Str("tag", name).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	resp, err := c.access.makeMethodRequest(ctx, http.MethodDelete, urlPath, c.auth, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete a tag")
+		return err
+	}
+	closeBody(ctx, resp)
+
+	return nil
+}
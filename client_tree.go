@@ -0,0 +1,50 @@
+package gitlab
+
+import (
+	"context"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func (c apiClient) Tree(ctx context.Context, project, path, ref string, recursive bool, opts ...*gitlabdata.ListOptions) ([]*gitlabdata.TreeNode, error) {
+	logger := c.log(ctx).With().Str("gitlab-request", "tree").Str("project", project).
+		Str("path", path).Str("ref", ref).Bool("recursive", recursive).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	var dest []*gitlabdata.TreeNode
+	nodes := c.TreeIter(ctx, project, path, ref, recursive, opts...)
+	for nodes.Next() {
+		dest = append(dest, nodes.Value())
+	}
+	if err := nodes.Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to get a tree")
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (c apiClient) TreeIter(ctx context.Context, project, path, ref string, recursive bool, opts ...*gitlabdata.ListOptions) TreeNodeIterator {
+	logger := c.log(ctx).With().Str("gitlab-request", "tree").Str("project", project).
+		Str("path", path).Str("ref", ref).Bool("recursive", recursive).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	keys := map[string]string{}
+	if len(path) > 0 {
+		keys["path"] = path
+	}
+	if len(ref) > 0 {
+		keys["ref"] = ref
+	}
+	if recursive {
+		keys["recursive"] = "true"
+	}
+
+	urlPath := c.projectURL(project, "repository", "tree")
+	return &treeNodeIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(urlPath, c.auth, keys, listOptions(opts)),
+		},
+	}
+}
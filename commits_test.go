@@ -0,0 +1,269 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// commitsServer serves master branch with commits "3" - "2" - "1", newest first. Commit SHAs are only accepted as
+// ref_name if shaRefs is set, abbreviated ones are never accepted. refNames collects ref_name values of commit list
+// requests
+func commitsServer(t *testing.T, shaRefs bool, refNames *[]string) *httptest.Server {
+	history := []string{strings.Repeat("3", 40), strings.Repeat("2", 40), strings.Repeat("1", 40)}
+	commit := func(id string) map[string]interface{} {
+		return map[string]interface{}{"id": id}
+	}
+	// find returns the position of the commit with the given SHA prefix in the history
+	find := func(ref string) int {
+		for i, id := range history {
+			if len(ref) >= 7 && strings.HasPrefix(id, ref) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := strings.TrimPrefix(r.URL.EscapedPath(), "/projects/g%2Fp/repository/")
+		switch {
+		case route == "commits":
+			ref := r.URL.Query().Get("ref_name")
+			*refNames = append(*refNames, ref)
+			pos := find(ref)
+			if ref == "master" {
+				pos = 0
+			} else if len(ref) != 40 || !shaRefs {
+				pos = -1
+			}
+			if pos < 0 {
+				http.NotFound(w, r)
+				return
+			}
+			var res []interface{}
+			for _, id := range history[pos:] {
+				res = append(res, commit(id))
+			}
+			json.NewEncoder(w).Encode(res)
+		case strings.HasSuffix(route, "/refs"):
+			if find(strings.TrimSuffix(strings.TrimPrefix(route, "commits/"), "/refs")) < 0 {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode([]interface{}{map[string]interface{}{"type": "branch", "name": "master"}})
+		case strings.HasPrefix(route, "commits/"):
+			pos := find(strings.TrimPrefix(route, "commits/"))
+			if pos < 0 {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(commit(history[pos]))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCommitsBySHA(t *testing.T) {
+	full := strings.Repeat("2", 40)
+	tests := []struct {
+		name     string
+		ref      string
+		shaRefs  bool
+		refNames []string
+	}{
+		{name: "full sha", ref: full, shaRefs: true, refNames: []string{full}},
+		{name: "abbreviated sha", ref: full[:8], shaRefs: true, refNames: []string{full[:8], full}},
+		// old gitlab versions do not accept SHAs at all, branches containing the commit are walked then
+		{name: "refs containing", ref: full[:8], shaRefs: false, refNames: []string{full[:8], full, "master"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refNames []string
+			client := NewAPIAccess(nil, commitsServer(t, tt.shaRefs, &refNames).URL).Client("token")
+
+			commits, err := client.Commits(context.Background(), "g/p", tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, commit := range commits {
+				ids = append(ids, commit.ID[:1])
+			}
+			if strings.Join(ids, "") != "21" {
+				t.Errorf("got commits %v, want 2 and 1", ids)
+			}
+			if strings.Join(refNames, ",") != strings.Join(tt.refNames, ",") {
+				t.Errorf("got ref_name values %v, want %v", refNames, tt.refNames)
+			}
+		})
+	}
+}
+
+func TestCommitsNotFound(t *testing.T) {
+	var refNames []string
+	client := NewAPIAccess(nil, commitsServer(t, true, &refNames).URL).Client("token")
+
+	if _, err := client.Commits(context.Background(), "g/p", "abcdefabcdef"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/commits/release%2Fv1": `{
+			"id": "bbb", "title": "fix", "parent_ids": ["aaa"],
+			"stats": {"additions": 3, "deletions": 1, "total": 4}
+		}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	commit, err := client.Commit(context.Background(), "g/p", "release/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.ID != "bbb" || commit.Title != "fix" || len(commit.ParentIDs) != 1 || commit.ParentIDs[0] != "aaa" {
+		t.Errorf("unexpected commit %+v", commit)
+	}
+	if stats := commit.Stats; stats == nil || stats.Additions != 3 || stats.Deletions != 1 || stats.Total != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/commits/release%2Fv1", url.Values{"stats": {"true"}})
+}
+
+func TestCommitDiff(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/commits/bbb/diff": `[
+			{"old_path": "a.go", "new_path": "b.go", "a_mode": "100644", "b_mode": "100755", "renamed_file": true, "diff": "@@ -1 +1 @@"},
+			{"old_path": "c.go", "new_path": "c.go", "deleted_file": true}
+		]`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	diffs, err := client.CommitDiff(context.Background(), "g/p", "bbb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("got %d diffs", len(diffs))
+	}
+	if d := diffs[0]; d.OldPath != "a.go" || d.NewPath != "b.go" || !d.RenamedFile || d.BMode != "100755" || d.Diff != "@@ -1 +1 @@" {
+		t.Errorf("unexpected diff %+v", d)
+	}
+	if d := diffs[1]; d.NewPath != "c.go" || !d.DeletedFile || d.RenamedFile {
+		t.Errorf("unexpected diff %+v", d)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/commits/bbb/diff", url.Values{})
+}
+
+func TestCompare(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/compare": `{
+			"commit": {"id": "ccc"},
+			"commits": [{"id": "bbb"}, {"id": "ccc"}],
+			"diffs": [{"old_path": "a.go", "new_path": "a.go", "diff": "@@ -1 +1 @@"}],
+			"compare_timeout": false,
+			"compare_same_ref": false,
+			"web_url": "https://gitlab.example.com/g/p/-/compare/v1.0.0...feature%2Fx"
+		}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	compare, err := client.Compare(context.Background(), "g/p", "v1.0.0", "feature/x", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compare.Commit.ID != "ccc" || len(compare.Commits) != 2 || compare.Commits[0].ID != "bbb" {
+		t.Errorf("unexpected commits %+v", compare)
+	}
+	if len(compare.Diffs) != 1 || compare.Diffs[0].NewPath != "a.go" || compare.CompareSameRef || compare.CompareTimeout {
+		t.Errorf("unexpected diffs %+v", compare)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/compare",
+		url.Values{"from": {"v1.0.0"}, "to": {"feature/x"}, "straight": {"false"}})
+
+	if _, err := client.Compare(context.Background(), "g/p", "v1.0.0", "feature/x", true); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, (*requests)[1], http.MethodGet, "/projects/g%2Fp/repository/compare",
+		url.Values{"from": {"v1.0.0"}, "to": {"feature/x"}, "straight": {"true"}})
+}
+
+func TestListCommits(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/commits": `[
+			{"id": "bbb", "author_name": "Jane", "stats": {"additions": 1, "deletions": 0, "total": 1}},
+			{"id": "aaa", "author_name": "Jane"}
+		]`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	since := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("MSK", 3*60*60))
+	commits, err := client.ListCommits(context.Background(), "g/p", "feature/x", &gitlabdata.CommitsOptions{
+		Path:        gitlabdata.String("sub/dir"),
+		Since:       gitlabdata.Time(since),
+		Until:       gitlabdata.Time(since.Add(24 * time.Hour)),
+		Author:      gitlabdata.String("jane@example.com"),
+		FirstParent: gitlabdata.Bool(true),
+		WithStats:   gitlabdata.Bool(true),
+		All:         gitlabdata.Bool(false),
+		Order:       gitlabdata.String(gitlabdata.CommitsOrderTopo),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].ID != "bbb" || commits[0].Stats == nil || commits[0].Stats.Total != 1 || commits[1].ID != "aaa" {
+		t.Errorf("unexpected commits %v", commits)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/commits", url.Values{
+		"ref_name":     {"feature/x"},
+		"path":         {"sub/dir"},
+		"since":        {"2020-01-02T03:04:05+03:00"},
+		"until":        {"2020-01-03T03:04:05+03:00"},
+		"author":       {"jane@example.com"},
+		"first_parent": {"true"},
+		"with_stats":   {"true"},
+		"all":          {"false"},
+		"order":        {"topo"},
+	})
+
+	// nil options and empty ref request the history of the default branch
+	iter := client.ListCommitsIter(context.Background(), "g/p", "", nil)
+	var ids []string
+	for iter.Next() {
+		ids = append(ids, iter.Value().ID)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "bbb,aaa" {
+		t.Errorf("got commits %v", ids)
+	}
+	checkRequest(t, (*requests)[1], http.MethodGet, "/projects/g%2Fp/repository/commits", url.Values{})
+
+	// list options are kept
+	commits, err = client.ListCommits(context.Background(), "g/p", "master", &gitlabdata.CommitsOptions{
+		ListOptions: gitlabdata.ListOptions{PerPage: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Errorf("got %d commits, want 2", len(commits))
+	}
+	checkRequest(t, (*requests)[2], http.MethodGet, "/projects/g%2Fp/repository/commits", url.Values{"ref_name": {"master"}})
+	if perPage := (*requests)[2].query.Get("per_page"); perPage != "5" {
+		t.Errorf("got per_page %s", perPage)
+	}
+}
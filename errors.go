@@ -0,0 +1,198 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ResponseError is returned when gitlab API responds with non-successful HTTP status code
+type ResponseError struct {
+	// StatusCode is HTTP status code of the response
+	StatusCode int
+
+	// Method and URL of the failed request
+	Method string
+	URL    string
+
+	// RequestID is a value of X-Request-Id header of the response, it is useful to find the request in gitlab logs
+	RequestID string
+
+	// Message is a human readable error message taken out of `message` field of the response. Validation errors
+	// are flattened into a single line
+	Message string
+
+	// ErrorCode and Description are taken from `error` and `error_description` fields of the response
+	ErrorCode   string
+	Description string
+
+	// Body is a raw response body
+	Body []byte
+
+	// Header is a set of response headers
+	Header http.Header
+}
+
+func (e *ResponseError) Error() string {
+	var buf strings.Builder
+	buf.WriteString("gitlab error: ")
+	buf.WriteString(e.Method)
+	buf.WriteByte(' ')
+	buf.WriteString(e.URL)
+	buf.WriteString(": ")
+	buf.WriteString(fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)))
+
+	switch {
+	case len(e.Message) > 0:
+		buf.WriteString(": ")
+		buf.WriteString(e.Message)
+	case len(e.ErrorCode) > 0:
+		buf.WriteString(": ")
+		buf.WriteString(e.ErrorCode)
+		if len(e.Description) > 0 {
+			buf.WriteString(": ")
+			buf.WriteString(e.Description)
+		}
+	case len(e.Body) > 0:
+		buf.WriteString(": ")
+		buf.Write(e.Body)
+	}
+
+	if len(e.RequestID) > 0 {
+		buf.WriteString(" (request id ")
+		buf.WriteString(e.RequestID)
+		buf.WriteByte(')')
+	}
+
+	return buf.String()
+}
+
+// Is makes errors.Is(err, os.ErrNotExist) to be true for 404 responses
+func (e *ResponseError) Is(target error) bool {
+	return target == os.ErrNotExist && e.StatusCode == http.StatusNotFound
+}
+
+// newResponseError creates an error for a response with non-successful status code and its read out body
+func newResponseError(resp *http.Response, body []byte) *ResponseError {
+	res := &ResponseError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+		Body:       body,
+		Header:     resp.Header,
+	}
+	if resp.Request != nil {
+		res.Method = resp.Request.Method
+		if resp.Request.URL != nil {
+			res.URL = resp.Request.URL.String()
+		}
+	}
+
+	var data struct {
+		Message     json.RawMessage `json:"message"`
+		Error       string          `json:"error"`
+		Description string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return res
+	}
+	res.Message = flattenMessage(data.Message)
+	res.ErrorCode = data.Error
+	res.Description = data.Description
+
+	return res
+}
+
+// flattenMessage turns gitlab `message` field into a single line. It can be a string or a map of validation errors
+// like {"name": ["is too long", "is invalid"]}
+func flattenMessage(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var msg string
+	if err := json.Unmarshal(raw, &msg); err == nil {
+		return msg
+	}
+
+	var fields map[string][]string
+	if err := json.Unmarshal(raw, &fields); err == nil {
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, key+" "+strings.Join(fields[key], ", "))
+		}
+		return strings.Join(items, "; ")
+	}
+
+	return string(raw)
+}
+
+// StatusCode returns HTTP status code of gitlab response the error was caused by or zero if it was not
+func StatusCode(err error) int {
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound checks if the error was caused by 404 Not Found response
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsUnauthorized checks if the error was caused by 401 Unauthorized response
+func IsUnauthorized(err error) bool {
+	return StatusCode(err) == http.StatusUnauthorized
+}
+
+// IsForbidden checks if the error was caused by 403 Forbidden response
+func IsForbidden(err error) bool {
+	return StatusCode(err) == http.StatusForbidden
+}
+
+// IsRateLimited checks if the error was caused by 429 Too Many Requests response
+func IsRateLimited(err error) bool {
+	return StatusCode(err) == http.StatusTooManyRequests
+}
+
+// IsConflict checks if the error was caused by 409 Conflict response
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+// IsServerError checks if the error was caused by 5xx response
+func IsServerError(err error) bool {
+	code := StatusCode(err)
+	return code >= 500 && code < 600
+}
+
+// IntegrityError is returned when the content of a file does not match its checksum reported by gitlab
+type IntegrityError struct {
+	Path string
+	Ref  string
+
+	// Expected is a checksum reported by gitlab and Actual is the one of the received content, both are hex
+	// encoded SHA-256 sums
+	Expected string
+	Actual   string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("content of %s at %s does not match its checksum: expected sha256 %s, got %s", e.Path, e.Ref, e.Expected, e.Actual)
+}
+
+// IsIntegrityError checks if the error was caused by a checksum mismatch of the received content
+func IsIntegrityError(err error) bool {
+	var integrityErr *IntegrityError
+	return errors.As(err, &integrityErr)
+}
@@ -0,0 +1,158 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResponseError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		message     string
+		errorCode   string
+		description string
+		text        string
+	}{
+		{
+			name:    "string message",
+			status:  http.StatusNotFound,
+			body:    `{"message":"404 Tag Not Found"}`,
+			message: "404 Tag Not Found",
+			text:    "404 Not Found: 404 Tag Not Found",
+		},
+		{
+			name:    "validation message",
+			status:  http.StatusBadRequest,
+			body:    `{"message":{"tag_name":["is invalid","is too long"],"ref":["is missing"]}}`,
+			message: "ref is missing; tag_name is invalid, is too long",
+			text:    "400 Bad Request: ref is missing; tag_name is invalid, is too long",
+		},
+		{
+			name:    "other message",
+			status:  http.StatusBadRequest,
+			body:    `{"message":["one","two"]}`,
+			message: `["one","two"]`,
+			text:    `400 Bad Request: ["one","two"]`,
+		},
+		{
+			name:        "error fields",
+			status:      http.StatusUnauthorized,
+			body:        `{"error":"invalid_token","error_description":"Token was revoked"}`,
+			errorCode:   "invalid_token",
+			description: "Token was revoked",
+			text:        "401 Unauthorized: invalid_token: Token was revoked",
+		},
+		{
+			name:   "plain body",
+			status: http.StatusBadGateway,
+			body:   "upstream is down",
+			text:   "502 Bad Gateway: upstream is down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-1")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, err := NewAPIAccess(nil, srv.URL).Client("token").Tag(context.Background(), "g/p", "v1.0.0")
+			var respErr *ResponseError
+			if !errors.As(err, &respErr) {
+				t.Fatalf("got error %v, expected *ResponseError", err)
+			}
+
+			if respErr.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d", respErr.StatusCode, tt.status)
+			}
+			if respErr.Method != http.MethodGet || respErr.URL != srv.URL+"/projects/g%2Fp/repository/tags/v1.0.0" {
+				t.Errorf("unexpected request %s %s", respErr.Method, respErr.URL)
+			}
+			if respErr.RequestID != "req-1" {
+				t.Errorf("got request id %q", respErr.RequestID)
+			}
+			if respErr.Message != tt.message {
+				t.Errorf("got message %q, want %q", respErr.Message, tt.message)
+			}
+			if respErr.ErrorCode != tt.errorCode || respErr.Description != tt.description {
+				t.Errorf("got error %q and description %q", respErr.ErrorCode, respErr.Description)
+			}
+			if string(respErr.Body) != tt.body {
+				t.Errorf("got body %q", respErr.Body)
+			}
+
+			want := "gitlab error: GET " + respErr.URL + ": " + tt.text + " (request id req-1)"
+			if got := respErr.Error(); got != want {
+				t.Errorf("got error text\n%s\nwant\n%s", got, want)
+			}
+			if got := errors.Is(err, os.ErrNotExist); got != (tt.status == http.StatusNotFound) {
+				t.Errorf("errors.Is(err, os.ErrNotExist) = %t for status %d", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestResponseErrorPredicates(t *testing.T) {
+	predicates := map[string]func(error) bool{
+		"IsNotFound":     IsNotFound,
+		"IsUnauthorized": IsUnauthorized,
+		"IsForbidden":    IsForbidden,
+		"IsRateLimited":  IsRateLimited,
+		"IsConflict":     IsConflict,
+		"IsServerError":  IsServerError,
+	}
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusNotFound, "IsNotFound"},
+		{http.StatusUnauthorized, "IsUnauthorized"},
+		{http.StatusForbidden, "IsForbidden"},
+		{http.StatusTooManyRequests, "IsRateLimited"},
+		{http.StatusConflict, "IsConflict"},
+		{http.StatusInternalServerError, "IsServerError"},
+		{http.StatusServiceUnavailable, "IsServerError"},
+		{http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		// predicates see through wrapping
+		err := fmt.Errorf("failed to get a tag: %w", &ResponseError{StatusCode: tt.status})
+		if got := StatusCode(err); got != tt.status {
+			t.Errorf("StatusCode = %d, want %d", got, tt.status)
+		}
+		for name, predicate := range predicates {
+			if got := predicate(err); got != (name == tt.want) {
+				t.Errorf("%s(%d) = %t", name, tt.status, got)
+			}
+		}
+	}
+
+	other := errors.New("connection refused")
+	if StatusCode(other) != 0 {
+		t.Error("errors other than responses must have no status code")
+	}
+	for name, predicate := range predicates {
+		if predicate(other) {
+			t.Errorf("%s is true for errors other than responses", name)
+		}
+	}
+}
+
+func TestResponseErrorWithoutRequest(t *testing.T) {
+	err := newResponseError(&http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}, nil)
+	if got := err.Error(); !strings.HasSuffix(got, "403 Forbidden") {
+		t.Errorf("unexpected error text %q", got)
+	}
+}
@@ -0,0 +1,584 @@
+package gitlab
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// Default extraction limits
+const (
+	DefaultExtractMaxFiles       = 100000
+	DefaultExtractMaxFileSize    = 1 << 30
+	DefaultExtractMaxTotalSize   = 4 << 30
+	DefaultExtractMaxArchiveSize = 4 << 30
+)
+
+var (
+	// ErrUnsafeArchivePath is returned when an archive entry or a symlink target points outside of the destination
+	ErrUnsafeArchivePath = errors.New("unsafe archive path")
+
+	// ErrArchiveLimit is returned when an archive exceeds extraction limits
+	ErrArchiveLimit = errors.New("archive exceeds extraction limits")
+)
+
+// ExtractOptions controls archive extraction. Zero limits mean defaults, negative ones disable the limit
+type ExtractOptions struct {
+	// Format is one of gitlabdata.ArchiveFormat* values, it is detected out of the content if empty
+	Format string
+
+	// MaxFiles limits the number of archive entries: files, directories and symlinks
+	MaxFiles int
+
+	// MaxFileSize limits the size of every extracted file
+	MaxFileSize int64
+
+	// MaxTotalSize limits the total size of extracted files
+	MaxTotalSize int64
+
+	// MaxArchiveSize limits the size of the archive itself, zip archives are kept in a temporary file until they
+	// are extracted
+	MaxArchiveSize int64
+}
+
+// ExtractArchive extracts an archive got from Archive into destDir, which is created if needed and is expected to
+// be empty. The top-level <project>-<sha> directory is stripped. Entries pointing outside of destDir, including
+// symlinks, symlinks resolved through other symlinks and hard links, are rejected with ErrUnsafeArchivePath and
+// exceeded limits are reported with ErrArchiveLimit. Everything extracted is removed in both cases. Executable
+// bits of files are preserved. opts can be nil. The source is not closed
+func ExtractArchive(ctx context.Context, src io.Reader, destDir string, opts *ExtractOptions) error {
+	x := &extractor{
+		ctx:          ctx,
+		dest:         filepath.Clean(destDir),
+		maxFiles:     DefaultExtractMaxFiles,
+		maxFileSize:  DefaultExtractMaxFileSize,
+		maxTotalSize: DefaultExtractMaxTotalSize,
+	}
+	maxArchiveSize := int64(DefaultExtractMaxArchiveSize)
+	var format string
+	if opts != nil {
+		format = opts.Format
+		if opts.MaxFiles != 0 {
+			x.maxFiles = opts.MaxFiles
+		}
+		if opts.MaxFileSize != 0 {
+			x.maxFileSize = opts.MaxFileSize
+		}
+		if opts.MaxTotalSize != 0 {
+			x.maxTotalSize = opts.MaxTotalSize
+		}
+		if opts.MaxArchiveSize != 0 {
+			maxArchiveSize = opts.MaxArchiveSize
+		}
+	}
+	if maxArchiveSize > 0 {
+		src = &archiveLimitReader{r: src, limit: maxArchiveSize, left: maxArchiveSize}
+	}
+
+	buf := bufio.NewReader(src)
+	if len(format) == 0 {
+		var err error
+		format, err = detectArchiveFormat(buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := existingEntries(x.dest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(x.dest, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	err = x.extract(buf, format)
+	if err == nil {
+		err = x.createSymlinks()
+	}
+	if errors.Is(err, ErrUnsafeArchivePath) || errors.Is(err, ErrArchiveLimit) {
+		// rejected archives must leave nothing behind, links leading outside included
+		if cleanupErr := removeExtracted(x.dest, existing); cleanupErr != nil {
+			return fmt.Errorf("%w (failed to remove extracted files: %v)", err, cleanupErr)
+		}
+	}
+	return err
+}
+
+// existingEntries lists names in the directory, nil if it does not exist
+func existingEntries(dir string) (map[string]bool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read destination directory: %w", err)
+	}
+	res := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		res[entry.Name()] = true
+	}
+	return res, nil
+}
+
+// removeExtracted removes entries of the directory except for existing ones, the directory itself is removed if it
+// did not exist. Symlinks are removed rather than followed
+func removeExtracted(dir string, existing map[string]bool) error {
+	if existing == nil {
+		return os.RemoveAll(dir)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if existing[entry.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) extract(buf *bufio.Reader, format string) error {
+	var err error
+	switch format {
+	case gitlabdata.ArchiveFormatZip:
+		err = x.extractZip(buf)
+	case gitlabdata.ArchiveFormatTar:
+		err = x.extractTar(buf)
+	case gitlabdata.ArchiveFormatTarGz:
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip stream: %w", err)
+		}
+		err = x.extractTar(gz)
+	case gitlabdata.ArchiveFormatTarBz2:
+		err = x.extractTar(bzip2.NewReader(buf))
+	default:
+		return fmt.Errorf("unsupported archive format %s", format)
+	}
+	return err
+}
+
+// detectArchiveFormat detects the format by magic numbers of the content
+func detectArchiveFormat(r *bufio.Reader) (string, error) {
+	head, err := r.Peek(4)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read archive header: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return gitlabdata.ArchiveFormatZip, nil
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return gitlabdata.ArchiveFormatTarGz, nil
+	case bytes.HasPrefix(head, []byte("BZh")):
+		return gitlabdata.ArchiveFormatTarBz2, nil
+	default:
+		return gitlabdata.ArchiveFormatTar, nil
+	}
+}
+
+type extractor struct {
+	ctx  context.Context
+	dest string
+
+	maxFiles     int
+	maxFileSize  int64
+	maxTotalSize int64
+
+	entries   int
+	totalSize int64
+	symlinks  []archiveSymlink
+}
+
+// archiveSymlink is created after everything else to not write files through symlinks
+type archiveSymlink struct {
+	path   string
+	target string
+}
+
+// target returns a destination path of the entry with stripped top-level directory, empty if the entry is to be
+// skipped
+func (x *extractor) target(name string) (string, error) {
+	name = strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "./")
+	pos := strings.IndexByte(name, '/')
+	if pos < 0 {
+		// the top-level directory itself
+		return "", nil
+	}
+	name = strings.Trim(name[pos+1:], "/")
+	if len(name) == 0 {
+		return "", nil
+	}
+
+	clean := path.Clean(name)
+	if path.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%s: %w", name, ErrUnsafeArchivePath)
+	}
+	return filepath.Join(x.dest, filepath.FromSlash(clean)), nil
+}
+
+func (x *extractor) mkdir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return nil
+}
+
+// entry counts an archive entry
+func (x *extractor) entry() error {
+	if err := x.ctx.Err(); err != nil {
+		return err
+	}
+
+	x.entries++
+	if x.maxFiles > 0 && x.entries > x.maxFiles {
+		return fmt.Errorf("more than %d entries: %w", x.maxFiles, ErrArchiveLimit)
+	}
+	return nil
+}
+
+func (x *extractor) writeFile(dest string, mode os.FileMode, size int64, r io.Reader) error {
+	limit := int64(-1)
+	if x.maxFileSize > 0 {
+		limit = x.maxFileSize
+	}
+	if x.maxTotalSize > 0 && (limit < 0 || x.maxTotalSize-x.totalSize < limit) {
+		limit = x.maxTotalSize - x.totalSize
+	}
+	if limit >= 0 && size > limit {
+		return fmt.Errorf("%s is too large: %w", dest, ErrArchiveLimit)
+	}
+
+	if err := x.mkdir(filepath.Dir(dest)); err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	written, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if limit >= 0 && written > limit {
+		return fmt.Errorf("%s is too large: %w", dest, ErrArchiveLimit)
+	}
+	x.totalSize += written
+
+	return nil
+}
+
+func (x *extractor) symlink(dest, target string) error {
+	linkTarget := filepath.FromSlash(target)
+	if filepath.IsAbs(linkTarget) {
+		return fmt.Errorf("%s -> %s: %w", dest, target, ErrUnsafeArchivePath)
+	}
+	resolved := filepath.Join(filepath.Dir(dest), linkTarget)
+	if !x.inside(resolved) {
+		return fmt.Errorf("%s -> %s: %w", dest, target, ErrUnsafeArchivePath)
+	}
+
+	x.symlinks = append(x.symlinks, archiveSymlink{path: dest, target: linkTarget})
+	return nil
+}
+
+// maxSymlinkDepth limits chains of symlinks the same way operating systems do
+const maxSymlinkDepth = 40
+
+// createSymlinks checks and creates collected symlinks. Every link is resolved against other links of the archive
+// before anything is created, so chains of them cannot lead outside even if they are dangling
+func (x *extractor) createSymlinks() error {
+	links := make(map[string]string, len(x.symlinks))
+	for _, link := range x.symlinks {
+		links[link.path] = link.target
+	}
+	resolved := make([]string, len(x.symlinks))
+	for i, link := range x.symlinks {
+		target, err := x.resolveLink(links, link.path, 0)
+		if err != nil {
+			return fmt.Errorf("%s -> %s: %w", link.path, link.target, err)
+		}
+		resolved[i] = target
+	}
+
+	for _, link := range x.symlinks {
+		if err := x.mkdir(filepath.Dir(link.path)); err != nil {
+			return err
+		}
+		if err := os.Symlink(link.target, link.path); err != nil {
+			return fmt.Errorf("failed to create symlink: %w", err)
+		}
+	}
+
+	// double check links on disk, dangling ones are checked by their nearest existing ancestor
+	realDest, err := filepath.EvalSymlinks(x.dest)
+	if err != nil {
+		return err
+	}
+	for i, link := range x.symlinks {
+		target, err := evalExisting(resolved[i])
+		if err != nil {
+			return err
+		}
+		if !within(realDest, target) {
+			return fmt.Errorf("%s -> %s: %w", link.path, link.target, ErrUnsafeArchivePath)
+		}
+	}
+
+	return nil
+}
+
+// resolveLink resolves the link with the given path using links of the archive. Links are only allowed to point to
+// files, directories and other links: paths passing through links are rejected, as .. after a link is resolved
+// physically rather than lexically
+func (x *extractor) resolveLink(links map[string]string, link string, depth int) (string, error) {
+	if depth >= maxSymlinkDepth {
+		return "", fmt.Errorf("too many levels of symlinks: %w", ErrUnsafeArchivePath)
+	}
+	if err := x.checkNoLinks(links, link); err != nil {
+		return "", err
+	}
+
+	target := filepath.Dir(link)
+	for _, name := range strings.Split(links[link], string(filepath.Separator)) {
+		if _, ok := links[target]; ok {
+			return "", fmt.Errorf("path goes through symlink %s: %w", target, ErrUnsafeArchivePath)
+		}
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			target = filepath.Dir(target)
+		default:
+			target = filepath.Join(target, name)
+		}
+		if !x.inside(target) {
+			return "", ErrUnsafeArchivePath
+		}
+	}
+
+	if _, ok := links[target]; ok {
+		return x.resolveLink(links, target, depth+1)
+	}
+	return target, nil
+}
+
+// checkNoLinks checks that parent directories of the path within destination are not links
+func (x *extractor) checkNoLinks(links map[string]string, p string) error {
+	for dir := filepath.Dir(p); x.inside(dir) && dir != x.dest; dir = filepath.Dir(dir) {
+		if _, ok := links[dir]; ok {
+			return fmt.Errorf("path goes through symlink %s: %w", dir, ErrUnsafeArchivePath)
+		}
+	}
+	return nil
+}
+
+// evalExisting evaluates symlinks of the path, missing components are appended to the nearest existing ancestor
+func evalExisting(p string) (string, error) {
+	var rest []string
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			for i := len(rest) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, rest[i])
+			}
+			return resolved, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", err
+		}
+		rest = append(rest, filepath.Base(p))
+		p = parent
+	}
+}
+
+func (x *extractor) inside(p string) bool {
+	return within(x.dest, p)
+}
+
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (x *extractor) extractTar(r io.Reader) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			continue
+		}
+		dest, err := x.target(header.Name)
+		if err != nil {
+			return err
+		}
+		if len(dest) == 0 {
+			continue
+		}
+		if err := x.entry(); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(dest)
+		case tar.TypeReg:
+			err = x.writeFile(dest, header.FileInfo().Mode(), header.Size, archive)
+		case tar.TypeSymlink:
+			err = x.symlink(dest, header.Linkname)
+		case tar.TypeLink:
+			// hard links can point to any file of the file system the destination is on
+			err = fmt.Errorf("%s: hard links are not supported: %w", header.Name, ErrUnsafeArchivePath)
+		default:
+			err = fmt.Errorf("%s: unsupported tar entry type %c: %w", header.Name, header.Typeflag, ErrUnsafeArchivePath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) extractZip(r io.Reader) (err error) {
+	// zip archives cannot be read without random access, keep it in a temporary file
+	tmp, err := ioutil.TempFile("", "gitlab-archive-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file for an archive: %w", err)
+	}
+	defer func() {
+		if closeErr := tmp.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(tmp.Name()); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return fmt.Errorf("failed to download an archive: %w", err)
+	}
+	archive, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	for _, file := range archive.File {
+		dest, err := x.target(file.Name)
+		if err != nil {
+			return err
+		}
+		if len(dest) == 0 {
+			continue
+		}
+		if err := x.entry(); err != nil {
+			return err
+		}
+
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.mkdir(dest)
+		case mode&os.ModeSymlink != 0:
+			err = x.zipSymlink(dest, file)
+		case mode.IsRegular():
+			err = x.zipFile(dest, file)
+		default:
+			err = fmt.Errorf("%s: unsupported zip entry mode %s: %w", file.Name, mode, ErrUnsafeArchivePath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *extractor) zipFile(dest string, file *zip.File) error {
+	r, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer r.Close()
+
+	return x.writeFile(dest, file.Mode(), int64(file.UncompressedSize64), r)
+}
+
+func (x *extractor) zipSymlink(dest string, file *zip.File) error {
+	r, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer r.Close()
+
+	target, err := ioutil.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read symlink %s: %w", file.Name, err)
+	}
+	return x.symlink(dest, string(target))
+}
+
+// archiveLimitReader fails with ErrArchiveLimit when more than limit bytes are read
+type archiveLimitReader struct {
+	r     io.Reader
+	limit int64
+	left  int64
+}
+
+func (r *archiveLimitReader) Read(p []byte) (int, error) {
+	// one byte over the limit tells archives of exactly the limit size from larger ones
+	if int64(len(p)) > r.left+1 {
+		p = p[:r.left+1]
+	}
+	n, err := r.r.Read(p)
+	r.left -= int64(n)
+	if r.left < 0 {
+		return n - 1, fmt.Errorf("archive is larger than %d bytes: %w", r.limit, ErrArchiveLimit)
+	}
+	return n, err
+}
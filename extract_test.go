@@ -0,0 +1,403 @@
+package gitlab
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testArchiveRoot = "p-0123456789abcdef0123456789abcdef01234567"
+
+// archiveEntry is an entry of a test archive, name is relative to the top-level directory
+type archiveEntry struct {
+	name string
+	body string
+	mode os.FileMode
+	link string // symlink target
+	hard bool   // link is a hard link
+}
+
+func file(name, body string) archiveEntry {
+	return archiveEntry{name: name, body: body, mode: 0644}
+}
+
+func symlink(name, target string) archiveEntry {
+	return archiveEntry{name: name, link: target}
+}
+
+func dir(name string) archiveEntry {
+	return archiveEntry{name: name + "/", mode: os.ModeDir | 0755}
+}
+
+func tarArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	write := func(header *tar.Header, body string) {
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(&tar.Header{Name: testArchiveRoot + "/", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	for _, entry := range entries {
+		name := testArchiveRoot + "/" + entry.name
+		switch {
+		case entry.hard:
+			write(&tar.Header{Name: name, Typeflag: tar.TypeLink, Linkname: entry.link}, "")
+		case len(entry.link) > 0:
+			write(&tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: entry.link}, "")
+		case entry.mode.IsDir():
+			write(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755}, "")
+		default:
+			write(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: int64(entry.mode), Size: int64(len(entry.body))}, entry.body)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(tarArchive(t, entries...)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	if _, err := archive.Create(testArchiveRoot + "/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: testArchiveRoot + "/" + entry.name, Method: zip.Deflate}
+		body := entry.body
+		if len(entry.link) > 0 {
+			header.SetMode(os.ModeSymlink | 0777)
+			body = entry.link
+		} else {
+			header.SetMode(entry.mode)
+		}
+		file, err := archive.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testTarBz2 is a tar.bz2 archive with a single hello.txt file containing "hello\n", there is no bzip2 encoder in
+// the standard library
+const testTarBz2 = "QlpoOTFBWSZTWaaxuPgAAJF7gMmQABBAA+eAIBBiRN5ACAggAHISpGg00yDQ0ZNNqCSSaNNAABoD7uuy9h6QEaEkIedGDohXixEgIQwEnLodwYzgpxgRtvMI2eVWtB9puaQpaDkSNRjsTuh8E7uD0qJkVQfoKUH4u5IpwoSFNY3HwA=="
+
+func extract(archive []byte, dest string, opts *ExtractOptions) error {
+	return ExtractArchive(context.Background(), bytes.NewReader(archive), dest, opts)
+}
+
+// listDir lists paths of everything in the directory, symlinks are not followed
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	res := []string{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir {
+			rel, _ := filepath.Rel(dir, p)
+			res = append(res, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestExtractArchiveFormats(t *testing.T) {
+	bz2, err := base64.StdEncoding.DecodeString(testTarBz2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []archiveEntry{file("hello.txt", "hello\n")}
+	archives := map[string][]byte{
+		"zip":     zipArchive(t, entries...),
+		"tar":     tarArchive(t, entries...),
+		"tar.gz":  tarGzArchive(t, entries...),
+		"tar.bz2": bz2,
+	}
+
+	for name, archive := range archives {
+		t.Run(name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := extract(archive, dest, nil); err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dest, "hello.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "hello\n" {
+				t.Errorf("unexpected content %q", data)
+			}
+		})
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	entries := []archiveEntry{
+		file("README.md", "readme"),
+		{name: "bin/run.sh", body: "#!/bin/sh\n", mode: 0755},
+		file("src/a/a.go", "package a\n"),
+		symlink("src/b", "a"),
+		symlink("docs", "README.md"),
+		symlink("dangling", "src/missing"),
+	}
+
+	for name, archive := range map[string][]byte{"zip": zipArchive(t, entries...), "tar": tarArchive(t, entries...)} {
+		t.Run(name, func(t *testing.T) {
+			// the destination is created if needed
+			dest := filepath.Join(t.TempDir(), "dest")
+			if err := extract(archive, dest, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(filepath.Join(dest, "src", "b", "a.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "package a\n" {
+				t.Errorf("unexpected content %q", data)
+			}
+			if target, err := os.Readlink(filepath.Join(dest, "dangling")); err != nil || target != filepath.FromSlash("src/missing") {
+				t.Errorf("unexpected dangling link %q: %v", target, err)
+			}
+
+			// executable bits are preserved
+			for name, perm := range map[string]os.FileMode{"bin/run.sh": 0755, "README.md": 0644, "src/a/a.go": 0644} {
+				info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm()&0111 != perm&0111 {
+					t.Errorf("%s has mode %s, expected %s", name, info.Mode().Perm(), perm)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractArchiveUnsafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []archiveEntry
+	}{
+		{
+			name:    "parent directory",
+			entries: []archiveEntry{file("a.txt", "a"), file("../escape.txt", "escape")},
+		},
+		{
+			name:    "parent directory in the middle",
+			entries: []archiveEntry{file("a/../../escape.txt", "escape")},
+		},
+		{
+			name:    "absolute symlink",
+			entries: []archiveEntry{file("a.txt", "a"), symlink("passwd", "/etc/passwd")},
+		},
+		{
+			name:    "symlink outside",
+			entries: []archiveEntry{file("a.txt", "a"), symlink("sub/up", "../..")},
+		},
+		{
+			// every link points inside lexically, but esc resolves x to the destination first
+			name: "symlink chain",
+			entries: []archiveEntry{
+				file("sub/a.txt", "a"),
+				symlink("sub/up", ".."),
+				symlink("x", "sub/up"),
+				symlink("esc", "x/.."),
+			},
+		},
+		{
+			name: "dangling symlink chain",
+			entries: []archiveEntry{
+				symlink("sub/up", ".."),
+				symlink("esc", "sub/up/../missing"),
+			},
+		},
+		{
+			name: "symlink inside symlink",
+			entries: []archiveEntry{
+				symlink("dir", "."),
+				symlink("dir/up", ".."),
+			},
+		},
+		{
+			name:    "symlink loop",
+			entries: []archiveEntry{symlink("a", "b"), symlink("b", "a")},
+		},
+	}
+
+	for _, tt := range tests {
+		for name, archive := range map[string][]byte{"zip": zipArchive(t, tt.entries...), "tar": tarArchive(t, tt.entries...)} {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				dest := filepath.Join(t.TempDir(), "dest")
+				err := extract(archive, dest, nil)
+				if !errors.Is(err, ErrUnsafeArchivePath) {
+					t.Fatalf("got error %v, expected ErrUnsafeArchivePath", err)
+				}
+				if _, err := os.Lstat(dest); !os.IsNotExist(err) {
+					t.Errorf("partial extraction is left: %v", listDir(t, dest))
+				}
+			})
+		}
+	}
+}
+
+func TestExtractArchiveHardLink(t *testing.T) {
+	archive := tarArchive(t,
+		file("a.txt", "a"),
+		archiveEntry{name: "passwd", link: "/etc/passwd", hard: true},
+	)
+
+	dest := filepath.Join(t.TempDir(), "dest")
+	if err := extract(archive, dest, nil); !errors.Is(err, ErrUnsafeArchivePath) {
+		t.Fatalf("got error %v, expected ErrUnsafeArchivePath", err)
+	}
+	if _, err := os.Lstat(dest); !os.IsNotExist(err) {
+		t.Errorf("partial extraction is left: %v", listDir(t, dest))
+	}
+}
+
+func TestExtractArchiveCleanupKeepsExisting(t *testing.T) {
+	dest := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dest, "existing"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := tarArchive(t, file("a/a.txt", "a"), symlink("a/up", "../.."))
+	if err := extract(archive, dest, nil); !errors.Is(err, ErrUnsafeArchivePath) {
+		t.Fatalf("got error %v, expected ErrUnsafeArchivePath", err)
+	}
+	if got := listDir(t, dest); len(got) != 1 || got[0] != "existing" {
+		t.Errorf("unexpected destination content %v", got)
+	}
+}
+
+func TestExtractArchiveLimits(t *testing.T) {
+	entries := []archiveEntry{
+		file("a.txt", strings.Repeat("a", 10)),
+		file("b.txt", strings.Repeat("b", 10)),
+		file("c.txt", strings.Repeat("c", 10)),
+	}
+	// directories and symlinks count as well
+	noFiles := []archiveEntry{dir("a"), dir("b"), symlink("c", "a")}
+
+	tests := []struct {
+		name    string
+		entries []archiveEntry
+		opts    ExtractOptions
+		ok      bool
+	}{
+		{name: "files", entries: entries, opts: ExtractOptions{MaxFiles: 2}},
+		{name: "directories and symlinks", entries: noFiles, opts: ExtractOptions{MaxFiles: 2}},
+		{name: "file size", entries: entries, opts: ExtractOptions{MaxFileSize: 9}},
+		{name: "total size", entries: entries, opts: ExtractOptions{MaxTotalSize: 29}},
+		{name: "archive size", entries: entries, opts: ExtractOptions{MaxArchiveSize: 100}},
+		{name: "exact", entries: entries, opts: ExtractOptions{MaxFiles: 3, MaxFileSize: 10, MaxTotalSize: 30}, ok: true},
+		{name: "exact entries", entries: noFiles, opts: ExtractOptions{MaxFiles: 3}, ok: true},
+		{name: "disabled", entries: entries, opts: ExtractOptions{MaxFiles: -1, MaxFileSize: -1, MaxTotalSize: -1, MaxArchiveSize: -1}, ok: true},
+	}
+
+	for _, tt := range tests {
+		archives := map[string][]byte{
+			"zip":    zipArchive(t, tt.entries...),
+			"tar":    tarArchive(t, tt.entries...),
+			"tar.gz": tarGzArchive(t, tt.entries...),
+		}
+		for name, archive := range archives {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				dest := filepath.Join(t.TempDir(), "dest")
+				err := extract(archive, dest, &tt.opts)
+				if tt.ok {
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got := listDir(t, dest); len(got) != 3 {
+						t.Errorf("unexpected destination content %v", got)
+					}
+					return
+				}
+
+				if !errors.Is(err, ErrArchiveLimit) {
+					t.Fatalf("got error %v, expected ErrArchiveLimit", err)
+				}
+				if _, err := os.Lstat(dest); !os.IsNotExist(err) {
+					t.Errorf("partial extraction is left: %v", listDir(t, dest))
+				}
+			})
+		}
+	}
+}
+
+func TestExtractArchiveSizeLimit(t *testing.T) {
+	archive := zipArchive(t, file("a.txt", "a"))
+
+	size := int64(len(archive))
+	if err := extract(archive, t.TempDir(), &ExtractOptions{MaxArchiveSize: size}); err != nil {
+		t.Errorf("archive of exactly the limit size must be accepted: %v", err)
+	}
+	if err := extract(archive, t.TempDir(), &ExtractOptions{MaxArchiveSize: size - 1}); !errors.Is(err, ErrArchiveLimit) {
+		t.Errorf("got error %v, expected ErrArchiveLimit", err)
+	}
+
+	// the archive is not read beyond the limit
+	src := io.MultiReader(bytes.NewReader(archive), infiniteReader{})
+	if err := ExtractArchive(context.Background(), src, t.TempDir(), &ExtractOptions{MaxArchiveSize: size}); !errors.Is(err, ErrArchiveLimit) {
+		t.Errorf("got error %v, expected ErrArchiveLimit", err)
+	}
+}
+
+// infiniteReader reads zeroes forever
+type infiniteReader struct{}
+
+func (infiniteReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
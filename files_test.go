@@ -0,0 +1,206 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// fileServer serves dir/file.txt at master with the given content and checksum
+func fileServer(t *testing.T, content, checksum string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/g%2Fp/repository/files/dir%2Ffile.txt" || r.URL.Query().Get("ref") != "master" {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"file_name":      "file.txt",
+			"file_path":      "dir/file.txt",
+			"size":           len(content),
+			"encoding":       "base64",
+			"content":        base64.StdEncoding.EncodeToString([]byte(content)),
+			"content_sha256": checksum,
+			"ref":            "master",
+			"blob_id":        "b10b",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFileWithInfo(t *testing.T) {
+	sum := sha256.Sum256([]byte("content"))
+	client := NewAPIAccess(nil, fileServer(t, "content", hex.EncodeToString(sum[:])).URL).Client("token")
+
+	content, info, err := client.FileWithInfo(context.Background(), "g/p", "dir/file.txt", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content" {
+		t.Errorf("got content %q", content)
+	}
+	if info.FilePath != "dir/file.txt" || info.Size != 7 || info.BlobID != "b10b" || info.Ref != "master" {
+		t.Errorf("unexpected file info %+v", info)
+	}
+}
+
+func TestFileWithInfoIntegrity(t *testing.T) {
+	sum := sha256.Sum256([]byte("expected content"))
+	expected := hex.EncodeToString(sum[:])
+	client := NewAPIAccess(nil, fileServer(t, "corrupted content", expected).URL).Client("token")
+
+	content, _, err := client.FileWithInfo(context.Background(), "g/p", "dir/file.txt", "master")
+	if content != nil {
+		t.Errorf("corrupted content %q is returned", content)
+	}
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) || !IsIntegrityError(err) {
+		t.Fatalf("got error %v, expected *IntegrityError", err)
+	}
+	actual := sha256.Sum256([]byte("corrupted content"))
+	if integrityErr.Path != "dir/file.txt" || integrityErr.Ref != "master" ||
+		integrityErr.Expected != expected || integrityErr.Actual != hex.EncodeToString(actual[:]) {
+		t.Errorf("unexpected error %+v", integrityErr)
+	}
+
+	// File is checked the same way
+	if _, err := client.File(context.Background(), "g/p", "dir/file.txt", "master"); !IsIntegrityError(err) {
+		t.Errorf("got error %v, expected integrity error", err)
+	}
+}
+
+// rawServer serves dir/file.txt at master with its metadata in headers and its blob b10b. Requests are recorded
+func rawServer(t *testing.T, content string) (*httptest.Server, *[]apiRequest) {
+	var requests []apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, apiRequest{method: r.Method, path: r.URL.EscapedPath(), query: r.URL.Query()})
+
+		header := w.Header()
+		switch r.URL.EscapedPath() {
+		case "/projects/g%2Fp/repository/files/dir%2Ffile.txt", "/projects/g%2Fp/repository/files/dir%2Ffile.txt/raw":
+			if r.URL.Query().Get("ref") != "master" {
+				http.NotFound(w, r)
+				return
+			}
+			header.Set("X-Gitlab-File-Path", "dir/file.txt")
+			header.Set("X-Gitlab-Size", strconv.Itoa(len(content)))
+			header.Set("X-Gitlab-Encoding", "base64")
+			header.Set("X-Gitlab-Ref", "master")
+			header.Set("X-Gitlab-Blob-Id", "b10b")
+			header.Set("X-Gitlab-Commit-Id", "c0c0")
+			header.Set("X-Gitlab-Last-Commit-Id", "1a57")
+			header.Set("X-Gitlab-Execute-Filemode", "true")
+		case "/projects/g%2Fp/repository/files/bad.txt":
+			header.Set("X-Gitlab-Size", "many")
+		case "/projects/g%2Fp/repository/blobs/b10b/raw":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		header.Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method != http.MethodHead {
+			w.Write([]byte(content))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestFileRaw(t *testing.T) {
+	srv, requests := rawServer(t, "raw content")
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	file, err := client.FileRaw(context.Background(), "g/p", "dir/file.txt", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "raw content" {
+		t.Errorf("got content %q", data)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/files/dir%2Ffile.txt/raw", url.Values{"ref": {"master"}})
+
+	if _, err := client.FileRaw(context.Background(), "g/p", "dir/file.txt", "develop"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	srv, requests := rawServer(t, "raw content")
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	info, err := client.FileMetadata(context.Background(), "g/p", "dir/file.txt", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := gitlabdata.FileInfo{
+		FileName:        "file.txt",
+		FilePath:        "dir/file.txt",
+		Size:            11,
+		Encoding:        "base64",
+		Ref:             "master",
+		BlobID:          "b10b",
+		CommitID:        "c0c0",
+		LastCommitID:    "1a57",
+		ExecuteFilemode: true,
+	}
+	if *info != want {
+		t.Errorf("got file info\n%+v\nwant\n%+v", *info, want)
+	}
+	checkRequest(t, (*requests)[0], http.MethodHead, "/projects/g%2Fp/repository/files/dir%2Ffile.txt", url.Values{"ref": {"master"}})
+
+	if _, err := client.FileMetadata(context.Background(), "g/p", "bad.txt", "master"); err == nil || IsNotFound(err) {
+		t.Errorf("got error %v, expected invalid size error", err)
+	}
+	if _, err := client.FileMetadata(context.Background(), "g/p", "missing.txt", "master"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
+
+func TestBlob(t *testing.T) {
+	srv, requests := rawServer(t, "blob content")
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	blob, err := client.Blob(context.Background(), "g/p", "b10b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blob.Close()
+	data, err := ioutil.ReadAll(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "blob content" {
+		t.Errorf("got content %q", data)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/blobs/b10b/raw", url.Values{})
+
+	info, err := client.BlobMetadata(context.Background(), "g/p", "b10b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (gitlabdata.FileInfo{Size: 12, BlobID: "b10b"}); *info != want {
+		t.Errorf("got blob info %+v, want %+v", *info, want)
+	}
+	checkRequest(t, (*requests)[1], http.MethodHead, "/projects/g%2Fp/repository/blobs/b10b/raw", url.Values{})
+
+	if _, err := client.Blob(context.Background(), "g/p", "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
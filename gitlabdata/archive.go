@@ -0,0 +1,20 @@
+package gitlabdata
+
+// ArchiveOptions represents the available Archive() options.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/repositories.html#get-file-archive
+type ArchiveOptions struct {
+	// Format is one of ArchiveFormat* values, ArchiveFormatZip is used by default
+	Format *string `url:"-" json:"-"`
+
+	// Path limits the archive to the given subdirectory of the repository
+	Path *string `url:"path,omitempty" json:"path,omitempty"`
+}
+
+// List of available archive formats
+const (
+	ArchiveFormatTarGz  = "tar.gz"
+	ArchiveFormatTarBz2 = "tar.bz2"
+	ArchiveFormatTar    = "tar"
+	ArchiveFormatZip    = "zip"
+)
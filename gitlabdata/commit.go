@@ -0,0 +1,79 @@
+package gitlabdata
+
+import "time"
+
+// Diff represents a GitLab diff.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/commits.html
+type Diff struct {
+	Diff        string `json:"diff"`
+	NewPath     string `json:"new_path"`
+	OldPath     string `json:"old_path"`
+	AMode       string `json:"a_mode"`
+	BMode       string `json:"b_mode"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+// Compare represents the result of a comparison of branches, tags or commits.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/repositories.html#compare-branches-tags-or-commits
+type Compare struct {
+	Commit         *Commit   `json:"commit"`
+	Commits        []*Commit `json:"commits"`
+	Diffs          []*Diff   `json:"diffs"`
+	CompareTimeout bool      `json:"compare_timeout"`
+	CompareSameRef bool      `json:"compare_same_ref"`
+	WebURL         string    `json:"web_url"`
+}
+
+// CommitRef represents the reference of branches/tags in a commit.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/commits.html#get-references-a-commit-is-pushed-to
+type CommitRef struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// List of available commit reference types
+const (
+	CommitRefBranch = "branch"
+	CommitRefTag    = "tag"
+	CommitRefAll    = "all"
+)
+
+// CommitsOptions represents the available ListCommits() options.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/commits.html#list-repository-commits
+type CommitsOptions struct {
+	ListOptions
+
+	// Path returns only commits touching the file or directory
+	Path *string `url:"path,omitempty" json:"path,omitempty"`
+
+	// Since and Until limit commits by their commit dates
+	Since *time.Time `url:"since,omitempty" json:"since,omitempty"`
+	Until *time.Time `url:"until,omitempty" json:"until,omitempty"`
+
+	// Author returns only commits of the author, it is matched against author name and email
+	Author *string `url:"author,omitempty" json:"author,omitempty"`
+
+	// FirstParent follows only the first parent commit upon seeing a merge commit
+	FirstParent *bool `url:"first_parent,omitempty" json:"first_parent,omitempty"`
+
+	// WithStats adds stats to every commit
+	WithStats *bool `url:"with_stats,omitempty" json:"with_stats,omitempty"`
+
+	// All returns commits of all refs of the repository
+	All *bool `url:"all,omitempty" json:"all,omitempty"`
+
+	// Order is either CommitsOrderDefault (reverse chronological) or CommitsOrderTopo
+	Order *string `url:"order,omitempty" json:"order,omitempty"`
+}
+
+// List of available commits orders
+const (
+	CommitsOrderDefault = "default"
+	CommitsOrderTopo    = "topo"
+)
@@ -2,6 +2,7 @@ package gitlabdata
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -14,8 +15,18 @@ type ListOptions struct {
 
 	// For paginated result sets, the number of results to include per page.
 	PerPage int `url:"per_page,omitempty" json:"per_page,omitempty"`
+
+	// MaxItems limits the total number of items retrieved across all pages, zero means no limit.
+	// It is not sent to GitLab and only used by the client to stop walking through pages.
+	MaxItems int `url:"-" json:"-"`
 }
 
+// List of available sort directions
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
 // VisibilityValue represents a visibility level within GitLab.
 //
 // GitLab API docs: https://docs.gitlab.com/ce/api/
@@ -125,6 +136,103 @@ const (
 // ISOTime represents an ISO 8601 formatted date
 type ISOTime time.Time
 
+// ISO 8601 date format
+const iso8601 = "2006-01-02"
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t ISOTime) MarshalJSON() ([]byte, error) {
+	if time.Time(t).IsZero() {
+		return []byte(`null`), nil
+	}
+
+	if y := time.Time(t).Year(); y < 0 || y >= 10000 {
+		// ISO 8601 uses 4 digits for the years.
+		return nil, errors.New("json: ISOTime year outside of range [0,9999]")
+	}
+
+	b := make([]byte, 0, len(iso8601)+2)
+	b = append(b, '"')
+	b = time.Time(t).AppendFormat(b, iso8601)
+	b = append(b, '"')
+
+	return b, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *ISOTime) UnmarshalJSON(data []byte) error {
+	// Ignore null, like in the main JSON package.
+	if string(data) == "null" {
+		return nil
+	}
+
+	isotime, err := time.Parse(`"`+iso8601+`"`, string(data))
+	if err != nil {
+		return err
+	}
+	*t = ISOTime(isotime)
+
+	return nil
+}
+
+// String implements the fmt.Stringer interface.
+func (t ISOTime) String() string {
+	return time.Time(t).Format(iso8601)
+}
+
+// Timestamp represents a moment in time as returned by GitLab. It tolerates formats used by different GitLab
+// versions and endpoints: RFC 3339 with or without fractional seconds, "2006-01-02 15:04:05 UTC" and dates
+// without time. Empty strings are treated as null. String returns RFC 3339 representation, the same one
+// commit dates were kept in when they were plain strings
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayouts are formats Timestamp is parsed from
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999 MST",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02T15:04:05.999999999",
+	iso8601,
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte(`null`), nil
+	}
+	return t.Time.MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	// Ignore null, like in the main JSON package.
+	if string(data) == "null" {
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if len(value) == 0 {
+		return nil
+	}
+	for _, layout := range timestampLayouts {
+		if moment, err := time.Parse(layout, value); err == nil {
+			t.Time = moment
+			return nil
+		}
+	}
+	return fmt.Errorf("json: cannot parse %q as a timestamp", value)
+}
+
+// String implements the fmt.Stringer interface.
+func (t Timestamp) String() string {
+	return t.Format(time.RFC3339)
+}
+
 // NotificationLevelValue represents a notification level.
 type NotificationLevelValue int
 
@@ -186,3 +294,27 @@ var notificationLevelTypes = map[string]NotificationLevelValue{
 	"mention":       MentionNotificationLevel,
 	"custom":        CustomNotificationLevel,
 }
+
+// String is a helper routine that allocates a new string value
+// to store v and returns a pointer to it.
+func String(v string) *string {
+	return &v
+}
+
+// Bool is a helper routine that allocates a new bool value
+// to store v and returns a pointer to it.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// Int is a helper routine that allocates a new int value
+// to store v and returns a pointer to it.
+func Int(v int) *int {
+	return &v
+}
+
+// Time is a helper routine that allocates a new time.Time value
+// to store v and returns a pointer to it.
+func Time(v time.Time) *time.Time {
+	return &v
+}
@@ -0,0 +1,98 @@
+package gitlabdata
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    time.Time
+		wantErr bool
+	}{
+		{data: `"2020-01-02T03:04:05Z"`, want: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{data: `"2020-01-02T06:04:05+03:00"`, want: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{data: `"2020-01-02T06:04:05.123+03:00"`, want: time.Date(2020, 1, 2, 3, 4, 5, 123000000, time.UTC)},
+		{data: `"2020-01-02T06:04:05+0300"`, want: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{data: `"2020-01-02 03:04:05 UTC"`, want: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{data: `"2020-01-02 06:04:05 +0300"`, want: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{data: `"2020-01-02T03:04:05"`, want: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{data: `"2020-01-02"`, want: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{data: `""`},
+		{data: `null`},
+		{data: `"yesterday"`, wantErr: true},
+		{data: `12345`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var got Timestamp
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unmarshal %s: error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("unmarshal %s: got %s, want %s", tt.data, got.Time, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampCommit(t *testing.T) {
+	var commit Commit
+	data := `{"id":"abc","committed_date":"2020-01-02T06:04:05.000+03:00","authored_date":"","created_at":null}`
+	if err := json.Unmarshal([]byte(data), &commit); err != nil {
+		t.Fatal(err)
+	}
+	if commit.CommittedDate == nil || commit.CommittedDate.String() != "2020-01-02T06:04:05+03:00" {
+		t.Errorf("unexpected committed date %v", commit.CommittedDate)
+	}
+	if commit.CreatedAt != nil {
+		t.Errorf("null created date must be kept nil, got %v", commit.CreatedAt)
+	}
+
+	res, err := json.Marshal(commit.CommittedDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != `"2020-01-02T06:04:05+03:00"` {
+		t.Errorf("unexpected marshaled timestamp %s", res)
+	}
+	if res, _ := json.Marshal(Timestamp{}); string(res) != "null" {
+		t.Errorf("zero timestamp must be marshaled as null, got %s", res)
+	}
+}
+
+func TestTagCreatedAt(t *testing.T) {
+	var tag Tag
+	if err := json.Unmarshal([]byte(`{"name":"v1.0.0","created_at":"2020-01-02T03:04:05Z"}`), &tag); err != nil {
+		t.Fatal(err)
+	}
+	if tag.CreatedAt == nil || !tag.CreatedAt.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected tag creation time %v", tag.CreatedAt)
+	}
+}
+
+func TestISOTime(t *testing.T) {
+	var value struct {
+		Due *ISOTime `json:"due"`
+	}
+	if err := json.Unmarshal([]byte(`{"due":"2020-01-02"}`), &value); err != nil {
+		t.Fatal(err)
+	}
+	if value.Due == nil || value.Due.String() != "2020-01-02" {
+		t.Fatalf("unexpected date %v", value.Due)
+	}
+
+	res, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != `{"due":"2020-01-02"}` {
+		t.Errorf("unexpected marshaled date %s", res)
+	}
+	if err := json.Unmarshal([]byte(`{"due":"02.01.2020"}`), &value); err == nil {
+		t.Error("dates in other formats must be rejected")
+	}
+}
@@ -0,0 +1,133 @@
+package gitlabdata
+
+import (
+	"time"
+)
+
+// Release represents a GitLab version release.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/index.html
+type Release struct {
+	TagName         string              `json:"tag_name"`
+	Name            string              `json:"name"`
+	Description     string              `json:"description"`
+	DescriptionHTML string              `json:"description_html"`
+	CreatedAt       *Timestamp          `json:"created_at"`
+	ReleasedAt      *Timestamp          `json:"released_at"`
+	Author          *User               `json:"author"`
+	Commit          *Commit             `json:"commit"`
+	Milestones      []*ReleaseMilestone `json:"milestones"`
+	UpcomingRelease bool                `json:"upcoming_release"`
+	CommitPath      string              `json:"commit_path"`
+	TagPath         string              `json:"tag_path"`
+	Evidences       []*ReleaseEvidence  `json:"evidences"`
+	Assets          *ReleaseAssets      `json:"assets"`
+}
+
+// ReleaseAssets represents assets of a release: source archives and links.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/index.html
+type ReleaseAssets struct {
+	Count   int                   `json:"count"`
+	Sources []*ReleaseAssetSource `json:"sources"`
+	Links   []*ReleaseLink        `json:"links"`
+}
+
+// ReleaseAssetSource represents a source archive of a release.
+type ReleaseAssetSource struct {
+	Format string `json:"format"`
+	URL    string `json:"url"`
+}
+
+// LinkTypeValue represents a release link type.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/links.html
+type LinkTypeValue string
+
+// List of available release link types
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/links.html
+const (
+	ImageLinkType   LinkTypeValue = "image"
+	OtherLinkType   LinkTypeValue = "other"
+	PackageLinkType LinkTypeValue = "package"
+	RunbookLinkType LinkTypeValue = "runbook"
+)
+
+// ReleaseLink represents a release asset link.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/links.html
+type ReleaseLink struct {
+	ID             int           `json:"id"`
+	Name           string        `json:"name"`
+	URL            string        `json:"url"`
+	DirectAssetURL string        `json:"direct_asset_url"`
+	External       bool          `json:"external"`
+	LinkType       LinkTypeValue `json:"link_type"`
+}
+
+// ReleaseMilestone represents a milestone associated with a release.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/index.html
+type ReleaseMilestone struct {
+	ID          int        `json:"id"`
+	IID         int        `json:"iid"`
+	ProjectID   int        `json:"project_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	DueDate     *ISOTime   `json:"due_date"`
+	StartDate   *ISOTime   `json:"start_date"`
+	CreatedAt   *Timestamp `json:"created_at"`
+	UpdatedAt   *Timestamp `json:"updated_at"`
+	WebURL      string     `json:"web_url"`
+}
+
+// ReleaseEvidence represents a release evidence collected by GitLab.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/user/project/releases/index.html#release-evidence
+type ReleaseEvidence struct {
+	SHA         string     `json:"sha"`
+	Filepath    string     `json:"filepath"`
+	CollectedAt *Timestamp `json:"collected_at"`
+}
+
+// CreateReleaseOptions represents CreateRelease() options.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/index.html#create-a-release
+type CreateReleaseOptions struct {
+	Name        *string               `url:"name,omitempty" json:"name,omitempty"`
+	TagName     *string               `url:"tag_name,omitempty" json:"tag_name,omitempty"`
+	Description *string               `url:"description,omitempty" json:"description,omitempty"`
+	Ref         *string               `url:"ref,omitempty" json:"ref,omitempty"`
+	Milestones  []string              `url:"milestones,omitempty" json:"milestones,omitempty"`
+	Assets      *ReleaseAssetsOptions `url:"assets,omitempty" json:"assets,omitempty"`
+	ReleasedAt  *time.Time            `url:"released_at,omitempty" json:"released_at,omitempty"`
+}
+
+// ReleaseAssetsOptions represents release assets in CreateRelease() options.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/index.html#create-a-release
+type ReleaseAssetsOptions struct {
+	Links []*ReleaseLinkOptions `url:"links,omitempty" json:"links,omitempty"`
+}
+
+// ReleaseLinkOptions represents a release asset link in CreateRelease() options.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/index.html#create-a-release
+type ReleaseLinkOptions struct {
+	Name     *string        `url:"name,omitempty" json:"name,omitempty"`
+	URL      *string        `url:"url,omitempty" json:"url,omitempty"`
+	FilePath *string        `url:"filepath,omitempty" json:"filepath,omitempty"`
+	LinkType *LinkTypeValue `url:"link_type,omitempty" json:"link_type,omitempty"`
+}
+
+// UpdateReleaseOptions represents UpdateRelease() options.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/releases/index.html#update-a-release
+type UpdateReleaseOptions struct {
+	Name        *string    `url:"name,omitempty" json:"name,omitempty"`
+	Description *string    `url:"description,omitempty" json:"description,omitempty"`
+	Milestones  []string   `url:"milestones,omitempty" json:"milestones,omitempty"`
+	ReleasedAt  *time.Time `url:"released_at,omitempty" json:"released_at,omitempty"`
+}
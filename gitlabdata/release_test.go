@@ -0,0 +1,44 @@
+package gitlabdata
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestReleaseUnmarshalJSON(t *testing.T) {
+	data := `{
+		"tag_name": "v1.0.0",
+		"created_at": "2020-01-02 03:04:05 UTC",
+		"released_at": "2020-01-02T06:04:05.000+03:00",
+		"commit": {"id": "abc", "committed_date": "2020-01-02T06:04:05+0300"},
+		"milestones": [{"title": "m1", "due_date": "2020-02-01", "created_at": "2020-01-01 00:00:00 +0000", "updated_at": null}],
+		"evidences": [{"sha": "e1", "collected_at": "2020-01-02T03:04:05"}]
+	}`
+
+	var release Release
+	if err := json.Unmarshal([]byte(data), &release); err != nil {
+		t.Fatal(err)
+	}
+
+	moment := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	dates := map[string]*Timestamp{
+		"created_at":             release.CreatedAt,
+		"released_at":            release.ReleasedAt,
+		"commit.committed_date":  release.Commit.CommittedDate,
+		"evidences.collected_at": release.Evidences[0].CollectedAt,
+	}
+	for name, date := range dates {
+		if date == nil || !date.Equal(moment) {
+			t.Errorf("%s = %v, want %s", name, date, moment)
+		}
+	}
+
+	milestone := release.Milestones[0]
+	if milestone.CreatedAt == nil || !milestone.CreatedAt.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("milestone created_at = %v", milestone.CreatedAt)
+	}
+	if milestone.UpdatedAt != nil {
+		t.Errorf("milestone updated_at = %v, want nil", milestone.UpdatedAt)
+	}
+}
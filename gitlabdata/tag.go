@@ -12,6 +12,9 @@ type Tag struct {
 	Release *Release `json:"release"`
 	Name    string   `json:"name"`
 	Message string   `json:"message"`
+
+	// CreatedAt is a creation time of annotated tags, it is nil for lightweight ones and with older GitLab versions
+	CreatedAt *Timestamp `json:"created_at"`
 }
 
 // Commit represents a GitLab commit.
@@ -23,25 +26,17 @@ type Commit struct {
 	Title          string           `json:"title"`
 	AuthorName     string           `json:"author_name"`
 	AuthorEmail    string           `json:"author_email"`
-	AuthoredDate   string           `json:"authored_date"`
+	AuthoredDate   *Timestamp       `json:"authored_date"`
 	CommitterName  string           `json:"committer_name"`
 	CommitterEmail string           `json:"committer_email"`
-	CommittedDate  string           `json:"committed_date"`
-	CreatedAt      string           `json:"created_at"`
+	CommittedDate  *Timestamp       `json:"committed_date"`
+	CreatedAt      *Timestamp       `json:"created_at"`
 	Message        string           `json:"message"`
 	ParentIDs      []string         `json:"parent_ids"`
 	Stats          *CommitStats     `json:"stats"`
 	Status         *BuildStateValue `json:"status"`
 }
 
-// Release represents a GitLab version release.
-//
-// GitLab API docs: https://docs.gitlab.com/ce/api/tags.html
-type Release struct {
-	TagName     string `json:"tag_name"`
-	Description string `json:"description"`
-}
-
 // CommitStats represents the number of added and deleted files in a commit.
 //
 // GitLab API docs: https://docs.gitlab.com/ce/api/commits.html
@@ -50,3 +45,30 @@ type CommitStats struct {
 	Deletions int `json:"deletions"`
 	Total     int `json:"total"`
 }
+
+// ListTagsOptions represents the available ListTags() options.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/tags.html#list-project-repository-tags
+type ListTagsOptions struct {
+	ListOptions
+
+	// Search returns tags with names containing the value. ^value and value$ forms return tags with names
+	// starting and ending with the value respectively
+	Search *string `url:"search,omitempty" json:"search,omitempty"`
+
+	// OrderBy is one of TagsOrderByName, TagsOrderByUpdated or TagsOrderByVersion, GitLab orders by update
+	// time by default
+	OrderBy *string `url:"order_by,omitempty" json:"order_by,omitempty"`
+
+	// Sort is either SortAsc or SortDesc
+	Sort *string `url:"sort,omitempty" json:"sort,omitempty"`
+}
+
+// List of available tags ordering fields
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/tags.html#list-project-repository-tags
+const (
+	TagsOrderByName    = "name"
+	TagsOrderByUpdated = "updated"
+	TagsOrderByVersion = "version"
+)
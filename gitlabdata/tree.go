@@ -0,0 +1,19 @@
+package gitlabdata
+
+// TreeNode represents a GitLab repository file or directory.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/repositories.html
+type TreeNode struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+}
+
+// List of available tree node types
+const (
+	TreeNodeBlob   = "blob"
+	TreeNodeTree   = "tree"
+	TreeNodeCommit = "commit" // submodule
+)
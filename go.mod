@@ -1,8 +1,8 @@
 module github.com/sirkon/gitlab
 
-go 1.12
+go 1.16
 
 require (
-	github.com/pkg/errors v0.8.1 // indirect
 	github.com/rs/zerolog v1.12.0
+	golang.org/x/mod v0.10.0
 )
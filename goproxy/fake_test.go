@@ -0,0 +1,255 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCommit is a commit of a fake repository, files are the whole tree at the commit
+type fakeCommit struct {
+	id    string
+	time  time.Time
+	files map[string]string
+}
+
+// fakeProject is a fake gitlab project with linear history on the default branch
+type fakeProject struct {
+	id      int
+	path    string
+	commits []*fakeCommit     // oldest first
+	tags    map[string]string // tag name -> commit id
+}
+
+// fakeGitlab serves the subset of gitlab API used by the proxy
+type fakeGitlab struct {
+	t        *testing.T
+	projects []*fakeProject
+}
+
+func newFakeGitlab(t *testing.T, projects ...*fakeProject) *httptest.Server {
+	srv := httptest.NewServer(&fakeGitlab{t: t, projects: projects})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fakeCommits creates a history of commits with files taken from the given trees, each tree is a change of the
+// previous one
+func fakeCommits(trees ...map[string]string) []*fakeCommit {
+	var res []*fakeCommit
+	files := map[string]string{}
+	for i, tree := range trees {
+		next := map[string]string{}
+		for name, content := range files {
+			next[name] = content
+		}
+		for name, content := range tree {
+			next[name] = content
+		}
+		files = next
+		res = append(res, &fakeCommit{
+			id:    strings.Repeat(strconv.Itoa(i+1), 40),
+			time:  time.Date(2020, 1, 2, 3, 4, 5+i, 0, time.UTC),
+			files: files,
+		})
+	}
+	return res
+}
+
+func (g *fakeGitlab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/projects/"), "/")
+	for i, part := range parts {
+		parts[i], _ = url.PathUnescape(part)
+	}
+
+	var project *fakeProject
+	for _, p := range g.projects {
+		if p.path == parts[0] || strconv.Itoa(p.id) == parts[0] {
+			project = p
+		}
+	}
+	if project == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	route := strings.Join(parts[1:], "/")
+	switch {
+	case route == "":
+		g.json(w, map[string]interface{}{
+			"id":                  project.id,
+			"path_with_namespace": project.path,
+			"default_branch":      "master",
+		})
+	case route == "repository/tags":
+		prefix := strings.TrimPrefix(query.Get("search"), "^")
+		var names []string
+		for name := range project.tags {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		res := []interface{}{}
+		for _, name := range names {
+			res = append(res, project.tag(name))
+		}
+		g.json(w, res)
+	case len(parts) == 4 && parts[1] == "repository" && parts[2] == "tags":
+		if _, ok := project.tags[parts[3]]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		g.json(w, project.tag(parts[3]))
+	case len(parts) == 4 && parts[1] == "repository" && parts[2] == "files":
+		commit := project.resolve(query.Get("ref"))
+		if commit == nil {
+			http.NotFound(w, r)
+			return
+		}
+		content, ok := commit.files[parts[3]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		g.json(w, map[string]interface{}{
+			"file_path": parts[3],
+			"size":      len(content),
+			"encoding":  "base64",
+			"content":   base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	case route == "repository/archive.zip":
+		commit := project.resolve(query.Get("sha"))
+		if commit == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(repoZip(g.t, "p-"+commit.id, commit.files))
+	case route == "repository/commits":
+		commit := project.resolve(query.Get("ref_name"))
+		if commit == nil {
+			http.NotFound(w, r)
+			return
+		}
+		res := []interface{}{}
+		for i := project.index(commit.id); i >= 0; i-- {
+			res = append(res, project.commit(project.commits[i]))
+		}
+		g.json(w, res)
+	case len(parts) == 4 && parts[1] == "repository" && parts[2] == "commits":
+		commit := project.resolve(parts[3])
+		if commit == nil {
+			http.NotFound(w, r)
+			return
+		}
+		g.json(w, project.commit(commit))
+	case route == "repository/merge_base":
+		refs := query["refs[]"]
+		if len(refs) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		a, b := project.resolve(refs[0]), project.resolve(refs[1])
+		if a == nil || b == nil {
+			http.NotFound(w, r)
+			return
+		}
+		// the history is linear, so the merge base is the older commit
+		if project.index(a.id) < project.index(b.id) {
+			g.json(w, project.commit(a))
+		} else {
+			g.json(w, project.commit(b))
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *fakeGitlab) json(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		g.t.Error(err)
+	}
+}
+
+func (p *fakeProject) index(id string) int {
+	for i, commit := range p.commits {
+		if commit.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolve finds a commit by branch name, tag name or SHA prefix
+func (p *fakeProject) resolve(ref string) *fakeCommit {
+	if ref == "master" || ref == "" {
+		return p.commits[len(p.commits)-1]
+	}
+	if id, ok := p.tags[ref]; ok {
+		return p.commits[p.index(id)]
+	}
+	for _, commit := range p.commits {
+		if len(ref) >= 7 && strings.HasPrefix(commit.id, ref) {
+			return commit
+		}
+	}
+	return nil
+}
+
+func (p *fakeProject) commit(commit *fakeCommit) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             commit.id,
+		"short_id":       commit.id[:8],
+		"committed_date": commit.time.In(time.FixedZone("", 3*3600)).Format(time.RFC3339),
+		"authored_date":  commit.time.Format(time.RFC3339),
+	}
+}
+
+func (p *fakeProject) tag(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":   name,
+		"commit": p.commit(p.commits[p.index(p.tags[name])]),
+	}
+}
+
+// repoZip builds a zip archive the way gitlab does: everything is put into a single top level directory
+func repoZip(t *testing.T, root string, files map[string]string) []byte {
+	t.Helper()
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	if _, err := archive.Create(root + "/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		file, err := archive.Create(fmt.Sprintf("%s/%s", root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
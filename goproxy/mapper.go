@@ -0,0 +1,66 @@
+package goproxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirkon/gitlab"
+)
+
+// Mapper maps module path (without major version suffix) into gitlab project path and a subdirectory of the module
+// in the project repository. Must return an error satisfying errors.Is(err, os.ErrNotExist) if there is no such
+// project
+type Mapper func(ctx context.Context, modulePath string) (project, subdir string, err error)
+
+// StaticMapper maps module paths under the given host right into project paths: host/group/project/sub/dir turns into
+// group/project project and sub/dir subdirectory. projectDepth is a number of path components of project paths,
+// 2 for group/project, 3 for group/subgroup/project, etc
+func StaticMapper(host string, projectDepth int) Mapper {
+	prefix := strings.TrimSuffix(host, "/") + "/"
+	return func(ctx context.Context, modulePath string) (string, string, error) {
+		if !strings.HasPrefix(modulePath, prefix) {
+			return "", "", fmt.Errorf("module %s is not served from %s: %w", modulePath, host, os.ErrNotExist)
+		}
+
+		parts := strings.Split(strings.TrimPrefix(modulePath, prefix), "/")
+		if len(parts) < projectDepth {
+			return "", "", fmt.Errorf("module %s path is too short for a project path: %w", modulePath, os.ErrNotExist)
+		}
+		return strings.Join(parts[:projectDepth], "/"), strings.Join(parts[projectDepth:], "/"), nil
+	}
+}
+
+// DiscoveryMapper maps module paths under the given host into gitlab projects looking for the longest existing
+// project path with ProjectInfo requests, so modules in nested groups and in project subdirectories are both
+// supported. Discovered projects are cached
+func DiscoveryMapper(client gitlab.Client, host string) Mapper {
+	prefix := strings.TrimSuffix(host, "/") + "/"
+	var cache sync.Map
+
+	return func(ctx context.Context, modulePath string) (string, string, error) {
+		if !strings.HasPrefix(modulePath, prefix) {
+			return "", "", fmt.Errorf("module %s is not served from %s: %w", modulePath, host, os.ErrNotExist)
+		}
+		parts := strings.Split(strings.TrimPrefix(modulePath, prefix), "/")
+
+		// gitlab projects always belong to a group or a user
+		for i := len(parts); i >= 2; i-- {
+			project := strings.Join(parts[:i], "/")
+			if _, ok := cache.Load(project); !ok {
+				if _, err := client.ProjectInfo(ctx, project); err != nil {
+					if gitlab.IsNotFound(err) {
+						continue
+					}
+					return "", "", err
+				}
+				cache.Store(project, struct{}{})
+			}
+			return project, strings.Join(parts[i:], "/"), nil
+		}
+
+		return "", "", fmt.Errorf("no project found for module %s: %w", modulePath, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,544 @@
+/*
+Package goproxy serves Go modules kept in gitlab repositories via GOPROXY protocol, see `go help goproxy`.
+Module versions are tags of repositories, modules in subdirectories are versioned with prefixed tags, e.g.
+sub/module/v1.2.3. Pseudo-versions of commits are served as well, the latest version of modules without tags is
+a pseudo-version of the default branch head. Branch names and commit SHAs sent by the go command as version queries
+are resolved into versions tagged at their commits or into pseudo-versions of them.
+*/
+package goproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/sirkon/gitlab"
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// Info is a version info as served by .info and @latest endpoints
+type Info struct {
+	Version string
+	Time    time.Time
+}
+
+// Proxy serves Go modules out of gitlab repositories. It implements http.Handler serving GOPROXY protocol
+// endpoints relative to the root path, use http.StripPrefix to serve them from elsewhere
+type Proxy struct {
+	client   gitlab.Client
+	mapper   Mapper
+	versions *gitlab.VersionResolver
+	zipOpts  *ZipOptions
+}
+
+// Option configures the proxy
+type Option func(p *Proxy)
+
+// WithZipOptions sets options of module zips conversion
+func WithZipOptions(opts *ZipOptions) Option {
+	return func(p *Proxy) {
+		p.zipOpts = opts
+	}
+}
+
+// New creates a proxy getting modules with the given client. mapper maps module paths into gitlab projects
+func New(client gitlab.Client, mapper Mapper, opts ...Option) *Proxy {
+	p := &Proxy{
+		client:   client,
+		mapper:   mapper,
+		versions: gitlab.NewVersionResolver(client),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// moduleRef is a module location in gitlab
+type moduleRef struct {
+	path      string
+	project   string
+	subdir    string
+	pathMajor string
+}
+
+// tagName returns a name of the tag for the given version of the module
+func (m *moduleRef) tagName(version string) string {
+	if len(m.subdir) == 0 {
+		return version
+	}
+	return m.subdir + "/" + version
+}
+
+func (p *Proxy) resolve(ctx context.Context, modulePath string) (*moduleRef, error) {
+	if err := module.CheckPath(modulePath); err != nil {
+		return nil, err
+	}
+	base, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok {
+		return nil, fmt.Errorf("invalid module path %s", modulePath)
+	}
+
+	project, subdir, err := p.mapper(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	return &moduleRef{
+		path:      modulePath,
+		project:   project,
+		subdir:    strings.Trim(subdir, "/"),
+		pathMajor: pathMajor,
+	}, nil
+}
+
+// moduleVersions returns versions of the module in ascending order
+func (p *Proxy) moduleVersions(ctx context.Context, ref *moduleRef) ([]*gitlab.Version, error) {
+	versions, err := p.versions.Versions(ctx, ref.project, ref.subdir)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*gitlab.Version
+	for _, v := range versions {
+		// Go only recognizes tags with v prefix and without build metadata
+		if len(v.Build) > 0 || !strings.HasPrefix(strings.TrimPrefix(v.Tag.Name, v.Prefix), "v") {
+			continue
+		}
+		if !module.MatchPathMajor(v.String(), ref.pathMajor) {
+			continue
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// List returns known versions of the module
+func (p *Proxy) List(ctx context.Context, modulePath string) ([]string, error) {
+	ref, err := p.resolve(ctx, modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := p.moduleVersions(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, len(versions))
+	for i, v := range versions {
+		res[i] = v.String()
+	}
+	return res, nil
+}
+
+// Latest returns the latest release of the module or the latest pre-release if there are no releases. It is
+// a pseudo-version of the default branch head if there are no versions at all
+func (p *Proxy) Latest(ctx context.Context, modulePath string) (*Info, error) {
+	ref, err := p.resolve(ctx, modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := p.moduleVersions(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return p.latestPseudoVersion(ctx, ref)
+	}
+
+	latest := versions[len(versions)-1]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].IsPrerelease() {
+			latest = versions[i]
+			break
+		}
+	}
+	return tagInfo(latest.String(), latest.Tag), nil
+}
+
+// latestPseudoVersion returns info of a pseudo-version of the default branch head
+func (p *Proxy) latestPseudoVersion(ctx context.Context, ref *moduleRef) (*Info, error) {
+	project, err := p.client.ProjectInfo(ctx, ref.project)
+	if err != nil {
+		return nil, err
+	}
+	if len(project.DefaultBranch) == 0 {
+		return nil, fmt.Errorf("module %s has no versions: %w", ref.path, os.ErrNotExist)
+	}
+
+	return p.pseudoVersion(ctx, ref, project.DefaultBranch)
+}
+
+// pseudoVersion returns info of a pseudo-version of the given git ref
+func (p *Proxy) pseudoVersion(ctx context.Context, ref *moduleRef, gitRef string) (*Info, error) {
+	version, err := p.versions.PseudoVersion(ctx, ref.project, ref.subdir, gitRef)
+	if err != nil {
+		return nil, err
+	}
+	moment, err := module.PseudoVersionTime(version)
+	if err != nil {
+		return nil, err
+	}
+	if !module.MatchPathMajor(version, ref.pathMajor) {
+		// tagged versions belong to another major version, the commit has no base version then
+		rev, err := module.PseudoVersionRev(version)
+		if err != nil {
+			return nil, err
+		}
+		version = module.PseudoVersion(module.PathMajorPrefix(ref.pathMajor), "", moment, rev)
+	}
+
+	return &Info{
+		Version: version,
+		Time:    moment,
+	}, nil
+}
+
+// versionRef returns a git ref (tag name or commit SHA) of the given module version and its info. Versions which
+// are not semantic ones are queries the go command sends for branches and commit SHAs, they are resolved into
+// commits served with their tagged versions or pseudo-versions
+func (p *Proxy) versionRef(ctx context.Context, ref *moduleRef, version string) (string, *Info, error) {
+	if !semver.IsValid(version) {
+		return p.queryRef(ctx, ref, version)
+	}
+	if err := module.Check(ref.path, version); err != nil {
+		return "", nil, err
+	}
+	if version != module.CanonicalVersion(version) {
+		return "", nil, fmt.Errorf("version %s is not canonical: %w", version, os.ErrNotExist)
+	}
+
+	if module.IsPseudoVersion(version) {
+		return p.pseudoVersionRef(ctx, ref, version)
+	}
+
+	tag, err := p.client.Tag(ctx, ref.project, ref.tagName(version))
+	if err != nil {
+		return "", nil, err
+	}
+	return tag.Name, tagInfo(version, tag), nil
+}
+
+// queryRef resolves a branch, tag or commit SHA into a commit. The highest version of the module tagged at the
+// commit is returned if there is any, a pseudo-version of the commit otherwise
+func (p *Proxy) queryRef(ctx context.Context, ref *moduleRef, query string) (string, *Info, error) {
+	commit, err := p.client.Commit(ctx, ref.project, query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	versions, err := p.moduleVersions(ctx, ref)
+	if err != nil {
+		return "", nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if v.Tag.Commit != nil && v.Tag.Commit.ID == commit.ID {
+			return v.Tag.Name, tagInfo(v.String(), v.Tag), nil
+		}
+	}
+
+	info, err := p.pseudoVersion(ctx, ref, commit.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	return commit.ID, info, nil
+}
+
+// pseudoVersionRef resolves a commit of the pseudo-version. The pseudo-version must match the commit time and
+// its base version, if any, must be tagged in the commit history the same way the go command checks it
+func (p *Proxy) pseudoVersionRef(ctx context.Context, ref *moduleRef, version string) (string, *Info, error) {
+	rev, err := module.PseudoVersionRev(version)
+	if err != nil {
+		return "", nil, err
+	}
+	moment, err := module.PseudoVersionTime(version)
+	if err != nil {
+		return "", nil, err
+	}
+	base, err := module.PseudoVersionBase(version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	commit, err := p.client.Commit(ctx, ref.project, rev)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(commit.ID, rev) {
+		// the revision is a name of a branch or a tag rather than a commit SHA prefix
+		return "", nil, fmt.Errorf("no commit %s for pseudo-version %s: %w", rev, version, os.ErrNotExist)
+	}
+	if commit.CommittedDate == nil || !commit.CommittedDate.UTC().Truncate(time.Second).Equal(moment) {
+		return "", nil, fmt.Errorf("pseudo-version %s does not match time of commit %s: %w", version, commit.ID, os.ErrNotExist)
+	}
+
+	if len(base) > 0 {
+		ok, err := p.client.IsAncestor(ctx, ref.project, ref.tagName(base), commit.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			return "", nil, fmt.Errorf("base %s of pseudo-version %s is not an ancestor of commit %s: %w", base, version, commit.ID, os.ErrNotExist)
+		}
+	}
+
+	return commit.ID, &Info{
+		Version: version,
+		Time:    moment,
+	}, nil
+}
+
+// Info returns info of the given module version
+func (p *Proxy) Info(ctx context.Context, modulePath, version string) (*Info, error) {
+	ref, err := p.resolve(ctx, modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, info, err := p.versionRef(ctx, ref, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Mod returns go.mod file of the given module version. A synthetic one is returned if the module has no go.mod
+func (p *Proxy) Mod(ctx context.Context, modulePath, version string) ([]byte, error) {
+	ref, err := p.resolve(ctx, modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	gitRef, _, err := p.versionRef(ctx, ref, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.client.File(ctx, ref.project, path.Join(ref.subdir, "go.mod"), gitRef)
+	if err != nil {
+		if gitlab.IsNotFound(err) {
+			return []byte(fmt.Sprintf("module %s\n", modulePath)), nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Zip writes a zip archive of the given module version. The archive is built in a temporary file first, so nothing
+// is written to dst if the archive cannot be built
+func (p *Proxy) Zip(ctx context.Context, modulePath, version string, dst io.Writer) (err error) {
+	ref, err := p.resolve(ctx, modulePath)
+	if err != nil {
+		return err
+	}
+
+	gitRef, info, err := p.versionRef(ctx, ref, version)
+	if err != nil {
+		return err
+	}
+
+	archive, err := p.client.Archive(ctx, ref.project, gitRef)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := archive.Close(); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to close archive")
+		}
+	}()
+
+	tmp, err := ioutil.TempFile("", "gitlab-module-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file for a module zip: %w", err)
+	}
+	defer func() {
+		if closeErr := tmp.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(tmp.Name()); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}()
+
+	skipped, err := ModuleZip(archive, tmp, modulePath, info.Version, ref.subdir, p.zipOpts)
+	if err != nil {
+		return err
+	}
+	for _, skip := range skipped {
+		zerolog.Ctx(ctx).Debug().Str("file", skip.Path).Err(skip.Reason).Msg("file left out of module zip")
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind a module zip: %w", err)
+	}
+	if _, err := io.Copy(dst, tmp); err != nil {
+		return fmt.Errorf("failed to write a module zip: %w", err)
+	}
+	return nil
+}
+
+// tagInfo builds version info out of the tag
+func tagInfo(version string, tag *gitlabdata.Tag) *Info {
+	res := &Info{
+		Version: version,
+	}
+	if tag.Commit == nil || tag.Commit.CommittedDate == nil {
+		return res
+	}
+
+	res.Time = tag.Commit.CommittedDate.UTC()
+	return res
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	urlPath := strings.TrimPrefix(r.URL.Path, "/")
+
+	if escaped := strings.TrimSuffix(urlPath, "/@latest"); escaped != urlPath {
+		modulePath, err := module.UnescapePath(escaped)
+		if err != nil {
+			p.fail(ctx, w, badRequest{err})
+			return
+		}
+		info, err := p.Latest(ctx, modulePath)
+		if err != nil {
+			p.fail(ctx, w, err)
+			return
+		}
+		p.writeJSON(ctx, w, info)
+		return
+	}
+
+	pos := strings.LastIndex(urlPath, "/@v/")
+	if pos < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	modulePath, err := module.UnescapePath(urlPath[:pos])
+	if err != nil {
+		p.fail(ctx, w, badRequest{err})
+		return
+	}
+	file := urlPath[pos+len("/@v/"):]
+
+	if file == "list" {
+		versions, err := p.List(ctx, modulePath)
+		if err != nil {
+			p.fail(ctx, w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		p.write(ctx, w, []byte(strings.Join(append(versions, ""), "\n")))
+		return
+	}
+
+	ext := path.Ext(file)
+	version, err := module.UnescapeVersion(strings.TrimSuffix(file, ext))
+	if err != nil {
+		p.fail(ctx, w, badRequest{err})
+		return
+	}
+
+	switch ext {
+	case ".info":
+		info, err := p.Info(ctx, modulePath, version)
+		if err != nil {
+			p.fail(ctx, w, err)
+			return
+		}
+		p.writeJSON(ctx, w, info)
+	case ".mod":
+		data, err := p.Mod(ctx, modulePath, version)
+		if err != nil {
+			p.fail(ctx, w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		p.write(ctx, w, data)
+	case ".zip":
+		w.Header().Set("Content-Type", "application/zip")
+		dst := &responseWriter{w: w}
+		if err := p.Zip(ctx, modulePath, version, dst); err != nil {
+			if dst.written {
+				// the status is sent already
+				zerolog.Ctx(ctx).Error().Err(err).Msg("failed to write a response")
+				return
+			}
+			p.fail(ctx, w, err)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// responseWriter tracks if anything is written into the response
+type responseWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.w.Write(p)
+}
+
+// badRequest is an error of malformed request URL
+type badRequest struct {
+	error
+}
+
+// fail reports an error, not found errors are reported with 404 status code as the go command expects
+func (p *Proxy) fail(ctx context.Context, w http.ResponseWriter, err error) {
+	var pathErr *module.InvalidPathError
+	var versionErr *module.InvalidVersionError
+	var moduleErr *module.ModuleError
+	var badReq badRequest
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("module not found")
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &pathErr), errors.As(err, &versionErr), errors.As(err, &moduleErr), errors.As(err, &badReq):
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("invalid module request")
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to serve module request")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (p *Proxy) writeJSON(ctx context.Context, w http.ResponseWriter, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		p.fail(ctx, w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	p.write(ctx, w, data)
+}
+
+func (p *Proxy) write(ctx context.Context, w http.ResponseWriter, data []byte) {
+	if _, err := w.Write(data); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to write a response")
+	}
+}
@@ -0,0 +1,462 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/module"
+
+	"github.com/sirkon/gitlab"
+)
+
+func testProxy(t *testing.T) (*Proxy, map[string]*fakeProject) {
+	root := &fakeProject{
+		id:   1,
+		path: "g/p",
+		commits: fakeCommits(
+			map[string]string{
+				"go.mod":  "module example.com/g/p\n",
+				"LICENSE": "license",
+				"p.go":    "package p\n",
+			},
+			map[string]string{
+				"sub/mod/go.mod": "module example.com/g/p/sub/mod\n",
+				"sub/mod/mod.go": "package mod\n",
+			},
+			map[string]string{
+				"p.go": "package p\n\nconst Version = 3\n",
+			},
+		),
+	}
+	root.tags = map[string]string{
+		"v1.0.0":         root.commits[0].id,
+		"v1.1.0-rc.1":    root.commits[1].id,
+		"sub/mod/v0.2.0": root.commits[1].id,
+		"release":        root.commits[1].id,
+		"v1.2.0+build":   root.commits[2].id,
+	}
+
+	major := &fakeProject{
+		id:   2,
+		path: "g/q",
+		commits: fakeCommits(
+			map[string]string{
+				"go.mod": "module example.com/g/q\n",
+				"q.go":   "package q\n",
+			},
+			map[string]string{
+				"go.mod": "module example.com/g/q/v2\n",
+			},
+		),
+	}
+	major.tags = map[string]string{
+		"v1.0.0": major.commits[0].id,
+		"v2.1.0": major.commits[1].id,
+	}
+
+	untagged := &fakeProject{
+		id:   3,
+		path: "g/u",
+		commits: fakeCommits(
+			map[string]string{"u.go": "package u\n"},
+			map[string]string{"go.mod": "module example.com/g/u\n"},
+		),
+	}
+
+	gl := newFakeGitlab(t, root, major, untagged)
+	client := gitlab.NewAPIAccess(nil, gl.URL).Client("token")
+	projects := map[string]*fakeProject{
+		"root":     root,
+		"major":    major,
+		"untagged": untagged,
+	}
+	return New(client, StaticMapper("example.com", 2)), projects
+}
+
+func get(t *testing.T, proxy *Proxy, urlPath string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+	return rec
+}
+
+func getOK(t *testing.T, proxy *Proxy, urlPath string) []byte {
+	t.Helper()
+	rec := get(t, proxy, urlPath)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: unexpected status %d: %s", urlPath, rec.Code, rec.Body.String())
+	}
+	return rec.Body.Bytes()
+}
+
+func getInfo(t *testing.T, proxy *Proxy, urlPath string) Info {
+	t.Helper()
+	var info Info
+	if err := json.Unmarshal(getOK(t, proxy, urlPath), &info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func zipFiles(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := map[string]string{}
+	for _, file := range archive.File {
+		src, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(src); err != nil {
+			t.Fatal(err)
+		}
+		src.Close()
+		res[file.Name] = buf.String()
+	}
+	return res
+}
+
+func TestProxyList(t *testing.T) {
+	proxy, _ := testProxy(t)
+
+	tests := []struct {
+		module string
+		want   string
+	}{
+		{"example.com/g/p", "v1.0.0\nv1.1.0-rc.1\n"},
+		{"example.com/g/p/sub/mod", "v0.2.0\n"},
+		{"example.com/g/q", "v1.0.0\n"},
+		{"example.com/g/q/v2", "v2.1.0\n"},
+		{"example.com/g/u", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			if got := string(getOK(t, proxy, "/"+tt.module+"/@v/list")); got != tt.want {
+				t.Errorf("got versions %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyInfo(t *testing.T) {
+	proxy, projects := testProxy(t)
+
+	info := getInfo(t, proxy, "/example.com/g/p/@v/v1.0.0.info")
+	if info.Version != "v1.0.0" || !info.Time.Equal(projects["root"].commits[0].time) {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	info = getInfo(t, proxy, "/example.com/g/p/sub/mod/@v/v0.2.0.info")
+	if info.Version != "v0.2.0" || !info.Time.Equal(projects["root"].commits[1].time) {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestProxyMod(t *testing.T) {
+	proxy, _ := testProxy(t)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/example.com/g/p/@v/v1.0.0.mod", "module example.com/g/p\n"},
+		{"/example.com/g/p/sub/mod/@v/v0.2.0.mod", "module example.com/g/p/sub/mod\n"},
+		{"/example.com/g/q/v2/@v/v2.1.0.mod", "module example.com/g/q/v2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := string(getOK(t, proxy, tt.path)); got != tt.want {
+				t.Errorf("got go.mod %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyZip(t *testing.T) {
+	proxy, _ := testProxy(t)
+
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{
+			path: "/example.com/g/p/@v/v1.1.0-rc.1.zip",
+			want: map[string]string{
+				"example.com/g/p@v1.1.0-rc.1/LICENSE": "license",
+				"example.com/g/p@v1.1.0-rc.1/go.mod":  "module example.com/g/p\n",
+				"example.com/g/p@v1.1.0-rc.1/p.go":    "package p\n",
+			},
+		},
+		{
+			path: "/example.com/g/p/sub/mod/@v/v0.2.0.zip",
+			want: map[string]string{
+				"example.com/g/p/sub/mod@v0.2.0/LICENSE": "license",
+				"example.com/g/p/sub/mod@v0.2.0/go.mod":  "module example.com/g/p/sub/mod\n",
+				"example.com/g/p/sub/mod@v0.2.0/mod.go":  "package mod\n",
+			},
+		},
+		{
+			path: "/example.com/g/q/v2/@v/v2.1.0.zip",
+			want: map[string]string{
+				"example.com/g/q/v2@v2.1.0/go.mod": "module example.com/g/q/v2\n",
+				"example.com/g/q/v2@v2.1.0/q.go":   "package q\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := get(t, proxy, tt.path)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+				t.Errorf("unexpected content type %s", ct)
+			}
+			if got := zipFiles(t, rec.Body.Bytes()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got files %v, want %v", keys(got), keys(tt.want))
+			}
+		})
+	}
+}
+
+func TestProxyLatest(t *testing.T) {
+	proxy, projects := testProxy(t)
+
+	// releases win over newer pre-releases
+	if info := getInfo(t, proxy, "/example.com/g/p/@latest"); info.Version != "v1.0.0" {
+		t.Errorf("unexpected latest version %s", info.Version)
+	}
+	if info := getInfo(t, proxy, "/example.com/g/q/v2/@latest"); info.Version != "v2.1.0" {
+		t.Errorf("unexpected latest version %s", info.Version)
+	}
+
+	// modules without tags are served with pseudo-versions of the default branch
+	head := projects["untagged"].commits[1]
+	want := module.PseudoVersion("", "", head.time, head.id[:12])
+	info := getInfo(t, proxy, "/example.com/g/u/@latest")
+	if info.Version != want || !info.Time.Equal(head.time) {
+		t.Fatalf("got latest %+v, want %s", info, want)
+	}
+	if info := getInfo(t, proxy, "/example.com/g/u/@v/"+want+".info"); info.Version != want {
+		t.Errorf("unexpected info of pseudo-version %+v", info)
+	}
+	if got := string(getOK(t, proxy, "/example.com/g/u/@v/"+want+".mod")); got != "module example.com/g/u\n" {
+		t.Errorf("unexpected go.mod of pseudo-version %q", got)
+	}
+	files := zipFiles(t, getOK(t, proxy, "/example.com/g/u/@v/"+want+".zip"))
+	if got := keys(files); !reflect.DeepEqual(got, []string{"example.com/g/u@" + want + "/go.mod", "example.com/g/u@" + want + "/u.go"}) {
+		t.Errorf("unexpected files of pseudo-version zip %v", got)
+	}
+}
+
+func TestProxyPseudoVersion(t *testing.T) {
+	proxy, projects := testProxy(t)
+	commits := projects["root"].commits
+
+	// v1.0.0 is tagged at the first commit, so the pseudo-version of the last one is based on it
+	want := module.PseudoVersion("v1", "v1.0.0", commits[2].time, commits[2].id[:12])
+	info := getInfo(t, proxy, "/example.com/g/p/@v/"+want+".info")
+	if info.Version != want || !info.Time.Equal(commits[2].time) {
+		t.Errorf("unexpected info %+v", info)
+	}
+	files := zipFiles(t, getOK(t, proxy, "/example.com/g/p/@v/"+want+".zip"))
+	if got := files["example.com/g/p@"+want+"/p.go"]; got != "package p\n\nconst Version = 3\n" {
+		t.Errorf("unexpected content of the pseudo-version %q", got)
+	}
+
+	invalid := []string{
+		// commit time does not match
+		module.PseudoVersion("v1", "v1.0.0", commits[1].time, commits[2].id[:12]),
+		// there is no such commit
+		module.PseudoVersion("v1", "v1.0.0", commits[2].time, "abcdefabcdef"),
+		// v1.1.0-rc.1 is tagged after the first commit
+		module.PseudoVersion("v1", "v1.1.0-rc.1", commits[0].time, commits[0].id[:12]),
+		// there is no base tag
+		module.PseudoVersion("v1", "v1.5.0", commits[2].time, commits[2].id[:12]),
+	}
+	for _, version := range invalid {
+		if rec := get(t, proxy, "/example.com/g/p/@v/"+version+".info"); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d: %s", version, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestProxyQuery(t *testing.T) {
+	proxy, projects := testProxy(t)
+	commits := projects["root"].commits
+
+	// the default branch head has no tagged version, v1.1.0-rc.1 is the latest one in its history
+	head := module.PseudoVersion("v1", "v1.1.0-rc.1", commits[2].time, commits[2].id[:12])
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "branch", query: "master", want: head},
+		{name: "full sha", query: commits[2].id, want: head},
+		{name: "abbreviated sha", query: commits[2].id[:12], want: head},
+		{name: "tagged sha", query: commits[0].id, want: "v1.0.0"},
+		{name: "non-version tag", query: "release", want: "v1.1.0-rc.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := getInfo(t, proxy, "/example.com/g/p/@v/"+tt.query+".info")
+			if info.Version != tt.want {
+				t.Errorf("got version %s, want %s", info.Version, tt.want)
+			}
+			if got := string(getOK(t, proxy, "/example.com/g/p/@v/"+tt.query+".mod")); got != "module example.com/g/p\n" {
+				t.Errorf("unexpected go.mod %q", got)
+			}
+
+			// zips of queries are built for the resolved version
+			files := zipFiles(t, getOK(t, proxy, "/example.com/g/p/@v/"+tt.query+".zip"))
+			if _, ok := files["example.com/g/p@"+tt.want+"/go.mod"]; !ok {
+				t.Errorf("unexpected files %v", keys(files))
+			}
+		})
+	}
+
+	// the resolved pseudo-version is served as well
+	if info := getInfo(t, proxy, "/example.com/g/p/@v/"+head+".info"); info.Version != head || !info.Time.Equal(commits[2].time) {
+		t.Errorf("unexpected info of the resolved pseudo-version %+v", info)
+	}
+}
+
+func TestProxyNotFound(t *testing.T) {
+	proxy, _ := testProxy(t)
+
+	paths := []string{
+		"/example.com/g/none/@v/list",
+		"/example.com/g/none/@latest",
+		"/other.com/g/p/@v/list",
+		"/example.com/g/p/@v/v9.9.9.info",
+		"/example.com/g/p/@v/v9.9.9.mod",
+		"/example.com/g/p/@v/v9.9.9.zip",
+		"/example.com/g/p/@v/v1.0.info",
+		"/example.com/g/p/@v/v2.1.0.info",
+		"/example.com/g/p/@v/unknown-branch.info",
+		"/example.com/g/p/@v/abcdefabcdef.zip",
+		"/example.com/g/p/@v/v1.0.0.unknown",
+		"/example.com/g/p/@v/",
+		"/example.com/g/p",
+		"/example.com/G/p/@v/list",
+	}
+	for _, urlPath := range paths {
+		if rec := get(t, proxy, urlPath); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected status 404, got %d: %s", urlPath, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/example.com/g/p/@v/list", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestProxyInternalError(t *testing.T) {
+	gl := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer gl.Close()
+	proxy := New(gitlab.NewAPIAccess(nil, gl.URL).Client("token"), StaticMapper("example.com", 2))
+
+	if rec := get(t, proxy, "/example.com/g/p/@v/list"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+}
+
+func keys(files map[string]string) []string {
+	var res []string
+	for name := range files {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestProxyZipFailure(t *testing.T) {
+	project := &fakeProject{
+		id:      1,
+		path:    "g/p",
+		commits: fakeCommits(map[string]string{"go.mod": "module example.com/g/p\n"}),
+	}
+	project.tags = map[string]string{"v1.0.0": project.commits[0].id}
+	fake := &fakeGitlab{t: t, projects: []*fakeProject{project}}
+
+	// the checksum of the last file is broken, so the module zip fails after its first files are written
+	random := rand.New(rand.NewSource(1))
+	var noise string
+	for len(noise) < 64<<10 {
+		noise += strconv.FormatUint(random.Uint64(), 36)
+	}
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"p-sha/", ""},
+		// large enough to not stay in write buffers even being compressed
+		{"p-sha/a.go", "package p\n\n// " + noise + "\n"},
+		{"p-sha/go.mod", "module example.com/g/p\n"},
+		{"p-sha/z.go", "package p\n"},
+	}
+	for _, f := range files {
+		file, err := writer.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := archive.Bytes()
+	pos := bytes.LastIndex(data, []byte("package p\n"))
+	data[pos] = 'P'
+
+	gl := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/repository/archive.zip") {
+			w.Write(data)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer gl.Close()
+	proxy := New(gitlab.NewAPIAccess(nil, gl.URL).Client("token"), StaticMapper("example.com", 2))
+
+	var dst bytes.Buffer
+	if err := proxy.Zip(context.Background(), "example.com/g/p", "v1.0.0", &dst); err == nil {
+		t.Fatal("broken archive must be rejected")
+	}
+	if dst.Len() > 0 {
+		t.Errorf("%d bytes are written for a broken archive", dst.Len())
+	}
+
+	rec := get(t, proxy, "/example.com/g/p/@v/v1.0.0.zip")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); strings.Contains(ct, "zip") {
+		t.Errorf("unexpected content type of an error %s", ct)
+	}
+}
@@ -0,0 +1,159 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// DefaultMaxArchiveSize is a default limit of a repository archive size
+const DefaultMaxArchiveSize = 1 << 30
+
+// ZipOptions controls conversion of repository archives into module zips
+type ZipOptions struct {
+	// MaxArchiveSize limits the size of a repository archive to convert, DefaultMaxArchiveSize is used if it is zero.
+	// Module zips are limited by golang.org/x/mod/zip limits besides
+	MaxArchiveSize int64
+}
+
+// ZipSkip describes a file of the repository archive left out of the module zip
+type ZipSkip struct {
+	Path   string
+	Reason error
+}
+
+// ModuleZip converts gitlab repository zip archive into a module zip compliant with golang.org/x/mod/zip. Only files
+// of the given subdirectory are taken, vendor directories, nested modules, symlinks and files with names rejected
+// by the go command are left out. LICENSE of the repository root is included into modules in subdirectories
+// having no LICENSE of their own, as the go command does. Files are ordered by path, so the output is reproducible.
+// opts can be nil. Returns a list of files left out of the module zip
+func ModuleZip(src io.Reader, dst io.Writer, modulePath, version, subdir string, opts *ZipOptions) (skipped []ZipSkip, err error) {
+	maxSize := int64(DefaultMaxArchiveSize)
+	if opts != nil && opts.MaxArchiveSize > 0 {
+		maxSize = opts.MaxArchiveSize
+	}
+
+	// zip archives cannot be read without random access, keep it in a temporary file
+	tmp, err := ioutil.TempFile("", "gitlab-archive-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a temporary file for an archive: %w", err)
+	}
+	defer func() {
+		if closeErr := tmp.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(tmp.Name()); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}()
+
+	size, err := io.Copy(tmp, io.LimitReader(src, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download an archive: %w", err)
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("archive is larger than %d bytes", maxSize)
+	}
+	archive, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read an archive: %w", err)
+	}
+
+	files, err := moduleFiles(archive, subdir)
+	if err != nil {
+		return nil, err
+	}
+
+	// CheckFiles error only summarizes invalid files which are skipped anyway, size limits are what matters
+	checked, _ := modzip.CheckFiles(files)
+	if checked.SizeError != nil {
+		return nil, checked.SizeError
+	}
+	for _, fileErr := range append(checked.Omitted, checked.Invalid...) {
+		skipped = append(skipped, ZipSkip{Path: fileErr.Path, Reason: fileErr.Err})
+	}
+
+	valid := make(map[string]bool, len(checked.Valid))
+	for _, name := range checked.Valid {
+		valid[name] = true
+	}
+	var validFiles []modzip.File
+	for _, file := range files {
+		if valid[file.Path()] {
+			validFiles = append(validFiles, file)
+		}
+	}
+
+	if err := modzip.Create(dst, module.Version{Path: modulePath, Version: version}, validFiles); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
+
+// moduleFiles lists files of the module in the given subdirectory of the archive sorted by path
+func moduleFiles(archive *zip.Reader, subdir string) ([]modzip.File, error) {
+	prefix := strings.Trim(subdir, "/")
+	if len(prefix) > 0 {
+		prefix += "/"
+	}
+
+	var files []modzip.File
+	var rootLicense *zip.File
+	haveLicense := false
+	for _, file := range archive.File {
+		// gitlab puts everything into <project>-<sha> directory
+		pos := strings.IndexByte(file.Name, '/')
+		if pos < 0 {
+			return nil, fmt.Errorf("unexpected file %s at the top level of an archive", file.Name)
+		}
+		name := file.Name[pos+1:]
+		if len(name) == 0 || strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		if len(prefix) > 0 && name == "LICENSE" {
+			rootLicense = file
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		name = name[len(prefix):]
+		if name == "LICENSE" {
+			haveLicense = true
+		}
+		files = append(files, archiveFile{name: name, file: file})
+	}
+	if !haveLicense && rootLicense != nil {
+		files = append(files, archiveFile{name: "LICENSE", file: rootLicense})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path() < files[j].Path()
+	})
+	return files, nil
+}
+
+// archiveFile is a file of the repository archive seen from the module root
+type archiveFile struct {
+	name string
+	file *zip.File
+}
+
+func (f archiveFile) Path() string {
+	return f.name
+}
+
+func (f archiveFile) Lstat() (os.FileInfo, error) {
+	return f.file.FileInfo(), nil
+}
+
+func (f archiveFile) Open() (io.ReadCloser, error) {
+	return f.file.Open()
+}
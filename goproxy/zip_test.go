@@ -0,0 +1,174 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testZipRoot = "p-0123456789abcdef0123456789abcdef01234567"
+
+func moduleZip(t *testing.T, archive []byte, modulePath, subdir string, opts *ZipOptions) (map[string]string, []ZipSkip) {
+	t.Helper()
+	var dst bytes.Buffer
+	skipped, err := ModuleZip(bytes.NewReader(archive), &dst, modulePath, "v1.0.0", subdir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zipFiles(t, dst.Bytes()), skipped
+}
+
+func TestModuleZipNestedModulesAndVendor(t *testing.T) {
+	archive := repoZip(t, testZipRoot, map[string]string{
+		"go.mod":                  "module example.com/g/p\n",
+		"p.go":                    "package p\n",
+		"internal/i.go":           "package internal\n",
+		"vendor/modules.txt":      "# example.com/dep v1.0.0\n",
+		"vendor/example.com/d.go": "package dep\n",
+		"nested/go.mod":           "module example.com/g/p/nested\n",
+		"nested/n.go":             "package nested\n",
+		"nested/deep/d.go":        "package deep\n",
+	})
+
+	// vendor/modules.txt is kept the same way the go command does
+	files, skipped := moduleZip(t, archive, "example.com/g/p", "", nil)
+	want := []string{
+		"example.com/g/p@v1.0.0/go.mod",
+		"example.com/g/p@v1.0.0/internal/i.go",
+		"example.com/g/p@v1.0.0/p.go",
+		"example.com/g/p@v1.0.0/vendor/modules.txt",
+	}
+	if got := keys(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("got files %v, want %v", got, want)
+	}
+
+	skippedPaths := map[string]bool{}
+	for _, skip := range skipped {
+		skippedPaths[skip.Path] = true
+	}
+	for _, name := range []string{"vendor/example.com/d.go", "nested/go.mod", "nested/n.go", "nested/deep/d.go"} {
+		if !skippedPaths[name] {
+			t.Errorf("%s is not reported as skipped", name)
+		}
+	}
+}
+
+func TestModuleZipSubdirectory(t *testing.T) {
+	archive := repoZip(t, testZipRoot, map[string]string{
+		"go.mod":          "module example.com/g/p\n",
+		"LICENSE":         "root license",
+		"p.go":            "package p\n",
+		"sub/mod/go.mod":  "module example.com/g/p/sub/mod\n",
+		"sub/mod/m.go":    "package mod\n",
+		"sub/other.go":    "package sub\n",
+		"sub/own/go.mod":  "module example.com/g/p/sub/own\n",
+		"sub/own/LICENSE": "own license",
+	})
+
+	// modules in subdirectories inherit the root license
+	files, _ := moduleZip(t, archive, "example.com/g/p/sub/mod", "sub/mod", nil)
+	want := map[string]string{
+		"example.com/g/p/sub/mod@v1.0.0/LICENSE": "root license",
+		"example.com/g/p/sub/mod@v1.0.0/go.mod":  "module example.com/g/p/sub/mod\n",
+		"example.com/g/p/sub/mod@v1.0.0/m.go":    "package mod\n",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got files %v, want %v", files, want)
+	}
+
+	// unless they have one of their own
+	files, _ = moduleZip(t, archive, "example.com/g/p/sub/own", "/sub/own/", nil)
+	if got := files["example.com/g/p/sub/own@v1.0.0/LICENSE"]; got != "own license" {
+		t.Errorf("unexpected license %q", got)
+	}
+}
+
+func TestModuleZipSizeLimit(t *testing.T) {
+	archive := repoZip(t, testZipRoot, map[string]string{
+		"go.mod": "module example.com/g/p\n",
+		"big.go": "package p\n\n// " + strings.Repeat("x", 4096) + "\n",
+	})
+
+	var dst bytes.Buffer
+	_, err := ModuleZip(bytes.NewReader(archive), &dst, "example.com/g/p", "v1.0.0", "", &ZipOptions{MaxArchiveSize: int64(len(archive) - 1)})
+	if err == nil {
+		t.Fatal("archive above the limit must be rejected")
+	}
+	if dst.Len() > 0 {
+		t.Error("nothing must be written for rejected archives")
+	}
+
+	files, _ := moduleZip(t, archive, "example.com/g/p", "", &ZipOptions{MaxArchiveSize: int64(len(archive))})
+	if len(files) != 2 {
+		t.Errorf("archive of exactly the limit size must be accepted, got %v", keys(files))
+	}
+}
+
+func TestModuleZipReproducible(t *testing.T) {
+	files := map[string]string{
+		"go.mod":      "module example.com/g/p\n",
+		"a.go":        "package p\n",
+		"b/b.go":      "package b\n",
+		"c/d/e/f.go":  "package f\n",
+		"README.md":   "readme",
+		"z/LICENSE":   "license",
+		"testdata/x":  "data",
+		"Makefile":    "all:\n",
+		".gitignore":  "*.o\n",
+		"b/b_test.go": "package b\n",
+	}
+	archive := repoZip(t, testZipRoot, files)
+
+	// the same files put into an archive in the reverse order must produce the same module zip
+	var reversed bytes.Buffer
+	writer := zip.NewWriter(&reversed)
+	names := keys(files)
+	for i := len(names) - 1; i >= 0; i-- {
+		file, err := writer.Create(testZipRoot + "/" + names[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(files[names[i]])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var outputs [][]byte
+	for _, src := range [][]byte{archive, archive, reversed.Bytes()} {
+		var dst bytes.Buffer
+		if _, err := ModuleZip(bytes.NewReader(src), &dst, "example.com/g/p", "v1.0.0", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, dst.Bytes())
+	}
+	for i := 1; i < len(outputs); i++ {
+		if !bytes.Equal(outputs[0], outputs[i]) {
+			t.Errorf("output %d differs from the first one", i)
+		}
+	}
+}
+
+func TestModuleZipInvalidArchive(t *testing.T) {
+	var dst bytes.Buffer
+	if _, err := ModuleZip(strings.NewReader("not a zip"), &dst, "example.com/g/p", "v1.0.0", "", nil); err == nil {
+		t.Error("invalid archive must be rejected")
+	}
+
+	// gitlab archives have a single top level directory
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	if _, err := writer.Create("go.mod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ModuleZip(&archive, &dst, "example.com/g/p", "v1.0.0", "", nil); err == nil {
+		t.Error("archive with files at the top level must be rejected")
+	}
+}
@@ -0,0 +1,303 @@
+package gitlab
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// CommitIterator iterates over commits fetching them page by page
+type CommitIterator interface {
+	// Next advances to the next commit. Returns false when there are no commits left, an error occurred
+	// or the context was cancelled
+	Next() bool
+
+	// Value returns the current commit
+	Value() *gitlabdata.Commit
+
+	// Err returns an error stopped the iteration if any
+	Err() error
+}
+
+// TagIterator iterates over tags fetching them page by page
+type TagIterator interface {
+	// Next advances to the next tag. Returns false when there are no tags left, an error occurred
+	// or the context was cancelled
+	Next() bool
+
+	// Value returns the current tag
+	Value() *gitlabdata.Tag
+
+	// Err returns an error stopped the iteration if any
+	Err() error
+}
+
+// BranchIterator iterates over branches fetching them page by page
+type BranchIterator interface {
+	// Next advances to the next branch. Returns false when there are no branches left, an error occurred
+	// or the context was cancelled
+	Next() bool
+
+	// Value returns the current branch
+	Value() *gitlabdata.Branch
+
+	// Err returns an error stopped the iteration if any
+	Err() error
+}
+
+// ReleaseIterator iterates over releases fetching them page by page
+type ReleaseIterator interface {
+	// Next advances to the next release. Returns false when there are no releases left, an error occurred
+	// or the context was cancelled
+	Next() bool
+
+	// Value returns the current release
+	Value() *gitlabdata.Release
+
+	// Err returns an error stopped the iteration if any
+	Err() error
+}
+
+// TreeNodeIterator iterates over repository tree nodes fetching them page by page
+type TreeNodeIterator interface {
+	// Next advances to the next node. Returns false when there are no nodes left, an error occurred
+	// or the context was cancelled
+	Next() bool
+
+	// Value returns the current node
+	Value() *gitlabdata.TreeNode
+
+	// Err returns an error stopped the iteration if any
+	Err() error
+}
+
+// DiffIterator iterates over per-file diffs fetching them page by page
+type DiffIterator interface {
+	// Next advances to the next diff. Returns false when there are no diffs left, an error occurred
+	// or the context was cancelled
+	Next() bool
+
+	// Value returns the current diff
+	Value() *gitlabdata.Diff
+
+	// Err returns an error stopped the iteration if any
+	Err() error
+}
+
+// CommitRefIterator iterates over branches and tags containing a commit fetching them page by page
+type CommitRefIterator interface {
+	// Next advances to the next ref. Returns false when there are no refs left, an error occurred
+	// or the context was cancelled
+	Next() bool
+
+	// Value returns the current ref
+	Value() *gitlabdata.CommitRef
+
+	// Err returns an error stopped the iteration if any
+	Err() error
+}
+
+// pageIterator fetches pages lazily, it is a base for typed iterators
+type pageIterator struct {
+	ctx   context.Context
+	pages *pager
+	err   error
+
+	// pos is an index of the next item of the current page of size items
+	pos  int
+	size int
+}
+
+// next advances to the next item fetching pages into page, which is a pointer to a slice of items, when needed.
+// Returns an index of the item in the page, false when there are no items left or the iteration was stopped
+func (it *pageIterator) next(page interface{}) (int, bool) {
+	if it.err != nil {
+		return 0, false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return 0, false
+	}
+
+	for it.pos >= it.size {
+		// items of the previous page are given away, they must not be reused by the decoder
+		items := reflect.ValueOf(page).Elem()
+		items.Set(reflect.Zero(items.Type()))
+
+		ok, err := decodePage(it.ctx, it.pages, page)
+		if err != nil {
+			zerolog.Ctx(it.ctx).Error().Err(err).Msg("failed to get the next page")
+			it.err = err
+			return 0, false
+		}
+		if !ok {
+			return 0, false
+		}
+		it.pos = 0
+		it.size = it.pages.take(items.Len())
+	}
+
+	it.pos++
+	return it.pos - 1, true
+}
+
+func (it *pageIterator) Err() error {
+	return it.err
+}
+
+type commitIterator struct {
+	pageIterator
+	page  []*gitlabdata.Commit
+	value *gitlabdata.Commit
+}
+
+func (it *commitIterator) Next() bool {
+	i, ok := it.next(&it.page)
+	it.value = nil
+	if ok {
+		it.value = it.page[i]
+	}
+	return ok
+}
+
+func (it *commitIterator) Value() *gitlabdata.Commit {
+	return it.value
+}
+
+type tagIterator struct {
+	pageIterator
+	page  []*gitlabdata.Tag
+	value *gitlabdata.Tag
+}
+
+func (it *tagIterator) Next() bool {
+	i, ok := it.next(&it.page)
+	it.value = nil
+	if ok {
+		it.value = it.page[i]
+	}
+	return ok
+}
+
+func (it *tagIterator) Value() *gitlabdata.Tag {
+	return it.value
+}
+
+type branchIterator struct {
+	pageIterator
+	page  []*gitlabdata.Branch
+	value *gitlabdata.Branch
+}
+
+func (it *branchIterator) Next() bool {
+	i, ok := it.next(&it.page)
+	it.value = nil
+	if ok {
+		it.value = it.page[i]
+	}
+	return ok
+}
+
+func (it *branchIterator) Value() *gitlabdata.Branch {
+	return it.value
+}
+
+type releaseIterator struct {
+	pageIterator
+	page  []*gitlabdata.Release
+	value *gitlabdata.Release
+}
+
+func (it *releaseIterator) Next() bool {
+	i, ok := it.next(&it.page)
+	it.value = nil
+	if ok {
+		it.value = it.page[i]
+	}
+	return ok
+}
+
+func (it *releaseIterator) Value() *gitlabdata.Release {
+	return it.value
+}
+
+type treeNodeIterator struct {
+	pageIterator
+	page  []*gitlabdata.TreeNode
+	value *gitlabdata.TreeNode
+}
+
+func (it *treeNodeIterator) Next() bool {
+	i, ok := it.next(&it.page)
+	it.value = nil
+	if ok {
+		it.value = it.page[i]
+	}
+	return ok
+}
+
+func (it *treeNodeIterator) Value() *gitlabdata.TreeNode {
+	return it.value
+}
+
+type diffIterator struct {
+	pageIterator
+	page  []*gitlabdata.Diff
+	value *gitlabdata.Diff
+}
+
+func (it *diffIterator) Next() bool {
+	i, ok := it.next(&it.page)
+	it.value = nil
+	if ok {
+		it.value = it.page[i]
+	}
+	return ok
+}
+
+func (it *diffIterator) Value() *gitlabdata.Diff {
+	return it.value
+}
+
+type commitRefIterator struct {
+	pageIterator
+	page  []*gitlabdata.CommitRef
+	value *gitlabdata.CommitRef
+}
+
+func (it *commitRefIterator) Next() bool {
+	i, ok := it.next(&it.page)
+	it.value = nil
+	if ok {
+		it.value = it.page[i]
+	}
+	return ok
+}
+
+func (it *commitRefIterator) Value() *gitlabdata.CommitRef {
+	return it.value
+}
+
+func (c apiClient) TagsIter(ctx context.Context, project string, opts ...*gitlabdata.ListOptions) TagIterator {
+	var options gitlabdata.ListTagsOptions
+	if listOpts := listOptions(opts); listOpts != nil {
+		options.ListOptions = *listOpts
+	}
+	return c.ListTagsIter(ctx, project, &options)
+}
+
+func (c apiClient) CommitsIter(ctx context.Context, project string, ref string, opts ...*gitlabdata.ListOptions) CommitIterator {
+	logger := c.log(ctx).With().Str("gitlab-request", "commits").Str("project", project).Str("ref", ref).Logger()
+	ctx = (&logger).WithContext(ctx)
+
+	urlPath := c.projectURL(project, "repository", "commits")
+	return &commitIterator{
+		pageIterator: pageIterator{
+			ctx:   ctx,
+			pages: c.access.newPager(urlPath, c.auth, map[string]string{"ref_name": ref}, listOptions(opts)),
+		},
+	}
+}
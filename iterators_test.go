@@ -0,0 +1,155 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// listServer serves total items of the route page by page, item renders an item with the given index.
+// Requests to other routes fail with 404
+func listServer(t *testing.T, route string, total int, item func(i int) string) (*httptest.Server, *int) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != route {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if (page-1)*perPage+perPage < total {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+		w.Write([]byte("["))
+		for i := (page - 1) * perPage; i < page*perPage && i < total; i++ {
+			if i > (page-1)*perPage {
+				w.Write([]byte(","))
+			}
+			w.Write([]byte(item(i)))
+		}
+		w.Write([]byte("]"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestTreeIter(t *testing.T) {
+	srv, requests := listServer(t, "/projects/g%2Fp/repository/tree", 25, func(i int) string {
+		return fmt.Sprintf(`{"name":"f%d","type":"blob","path":"dir/f%d"}`, i, i)
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	nodes := client.TreeIter(context.Background(), "g/p", "dir", "master", true, &gitlabdata.ListOptions{PerPage: 10})
+	var count int
+	for nodes.Next() {
+		if name := nodes.Value().Name; name != fmt.Sprintf("f%d", count) {
+			t.Fatalf("node %d is %s", count, name)
+		}
+		count++
+		if count == 5 {
+			break
+		}
+	}
+	if err := nodes.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *requests != 1 {
+		t.Errorf("pages must be fetched lazily, got %d requests for the first page", *requests)
+	}
+
+	tree, err := client.Tree(context.Background(), "g/p", "dir", "master", true, &gitlabdata.ListOptions{PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 25 {
+		t.Errorf("got %d nodes, want 25", len(tree))
+	}
+
+	tree, err = client.Tree(context.Background(), "g/p", "", "", false, &gitlabdata.ListOptions{PerPage: 10, MaxItems: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 12 {
+		t.Errorf("got %d nodes, want 12", len(tree))
+	}
+
+	if _, err := client.Tree(context.Background(), "g/other", "", "", false); !IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	nodes = client.TreeIter(context.Background(), "g/other", "", "", false)
+	if nodes.Next() || !IsNotFound(nodes.Err()) {
+		t.Errorf("expected not found error, got %v", nodes.Err())
+	}
+}
+
+func TestCommitDiffIter(t *testing.T) {
+	srv, _ := listServer(t, "/projects/g%2Fp/repository/commits/abc/diff", 15, func(i int) string {
+		return fmt.Sprintf(`{"new_path":"f%d","old_path":"f%d"}`, i, i)
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	diffs, err := client.CommitDiff(context.Background(), "g/p", "abc", &gitlabdata.ListOptions{PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 15 || diffs[14].NewPath != "f14" {
+		t.Errorf("unexpected diffs %v", diffs)
+	}
+
+	iter := client.CommitDiffIter(context.Background(), "g/p", "abc", &gitlabdata.ListOptions{PerPage: 10, MaxItems: 3})
+	var count int
+	for iter.Next() {
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("got %d diffs, want 3", count)
+	}
+}
+
+func TestRefsContainingIter(t *testing.T) {
+	srv, _ := listServer(t, "/projects/g%2Fp/repository/commits/abc/refs", 150, func(i int) string {
+		return fmt.Sprintf(`{"type":"branch","name":"b%d"}`, i)
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	refs, err := client.RefsContaining(context.Background(), "g/p", "abc", gitlabdata.CommitRefBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 150 || refs[149].Name != "b149" {
+		t.Errorf("got %d refs", len(refs))
+	}
+	// items of earlier pages are not overwritten by later ones
+	for i, ref := range refs {
+		if ref.Name != fmt.Sprintf("b%d", i) {
+			t.Fatalf("got ref %s at %d", ref.Name, i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	iter := client.RefsContainingIter(ctx, "g/p", "abc", "")
+	if !iter.Next() {
+		t.Fatal(iter.Err())
+	}
+	cancel()
+	// the rest of the fetched page is not given away after the cancellation
+	if iter.Next() {
+		t.Errorf("got ref %v after the cancellation", iter.Value())
+	}
+	if iter.Value() != nil {
+		t.Error("the value is kept after the iteration stopped")
+	}
+	if err := iter.Err(); err != context.Canceled {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package gitlab
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+// Option configures API access
+type Option func(a *apiAccess)
+
+// RequestHook is called right before every attempt to send a request to gitlab API
+type RequestHook func(req *http.Request)
+
+// ResponseHook is called after every attempt to send a request to gitlab API. resp is nil when err is not
+// and response body must not be consumed by the hook
+type ResponseHook func(req *http.Request, resp *http.Response, err error)
+
+// WithHTTPClient sets HTTP client to make requests with, http.DefaultClient is used by default
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *apiAccess) {
+		if client != nil {
+			a.client = client
+		}
+	}
+}
+
+// WithUserAgent sets User-Agent header for all requests
+func WithUserAgent(userAgent string) Option {
+	return func(a *apiAccess) {
+		a.userAgent = userAgent
+	}
+}
+
+// WithHeader adds a header sent with every request
+func WithHeader(key, value string) Option {
+	return func(a *apiAccess) {
+		if a.headers == nil {
+			a.headers = http.Header{}
+		}
+		a.headers.Add(key, value)
+	}
+}
+
+// WithLogger sets a logger to be used when the request context has no logger attached with zerolog
+func WithLogger(logger zerolog.Logger) Option {
+	return func(a *apiAccess) {
+		a.logger = &logger
+	}
+}
+
+// WithRetryPolicy sets a policy to retry failed requests with. Requests are not retried by default
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(a *apiAccess) {
+		a.retry = policy
+	}
+}
+
+// WithRateLimiter sets a rate limiter shared by all clients spawned by API access
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(a *apiAccess) {
+		a.limiter = limiter
+	}
+}
+
+// WithCache sets a cache to keep GET responses in and revalidate them using ETag
+func WithCache(cache Cache) Option {
+	return func(a *apiAccess) {
+		a.cache = cache
+	}
+}
+
+// WithRequestHook adds a hook called before every attempt to send a request
+func WithRequestHook(hook RequestHook) Option {
+	return func(a *apiAccess) {
+		a.requestHooks = append(a.requestHooks, hook)
+	}
+}
+
+// WithResponseHook adds a hook called after every attempt to send a request
+func WithResponseHook(hook ResponseHook) Option {
+	return func(a *apiAccess) {
+		a.responseHooks = append(a.responseHooks, hook)
+	}
+}
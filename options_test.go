@@ -0,0 +1,218 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"gitlab.com", "https://gitlab.com/api/v4"},
+		{"https://gitlab.com", "https://gitlab.com/api/v4"},
+		{"https://gitlab.com/", "https://gitlab.com/api/v4"},
+		{"gitlab.com/api/v4", "https://gitlab.com/api/v4"},
+		{"https://gitlab.com/api/v4/", "https://gitlab.com/api/v4"},
+		{" http://gitlab.local:8080/gitlab/api/v4// ", "http://gitlab.local:8080/gitlab/api/v4"},
+	}
+	for _, tt := range tests {
+		access, err := New(tt.url)
+		if err != nil {
+			t.Errorf("New(%q): %v", tt.url, err)
+			continue
+		}
+		if got := access.(*apiAccess).url; got != tt.want {
+			t.Errorf("New(%q) url = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "ftp://gitlab.com", "https://", "https://gitlab.com/api/v4?private_token=x", "https://gitlab.com/#api"} {
+		if _, err := New(invalid); err == nil {
+			t.Errorf("New(%q) must fail", invalid)
+		}
+	}
+
+	// the compatibility constructor keeps the path as is and takes URLs it cannot normalise
+	if got := NewAPIAccess(nil, "https://gitlab.com/").(*apiAccess).url; got != "https://gitlab.com" {
+		t.Errorf("NewAPIAccess url = %s", got)
+	}
+	if got := NewAPIAccess(nil, "unix:///socket").(*apiAccess).url; got != "unix:///socket" {
+		t.Errorf("NewAPIAccess url = %s", got)
+	}
+}
+
+// countingTransport counts requests sent with it
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// recordingLimiter records identities of requests
+type recordingLimiter struct {
+	waits   []string
+	updates []string
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context, identity string) error {
+	l.waits = append(l.waits, identity)
+	return nil
+}
+
+func (l *recordingLimiter) Update(identity string, header http.Header) {
+	l.updates = append(l.updates, identity)
+}
+
+func TestOptions(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if ua := r.Header.Get("User-Agent"); ua != "tests/1.0" {
+			t.Errorf("got User-Agent %q", ua)
+		}
+		if values := r.Header.Values("X-Custom"); strings.Join(values, ",") != "a,b" {
+			t.Errorf("got X-Custom %v", values)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":1,"path_with_namespace":"g/p"}`))
+	}))
+	defer srv.Close()
+
+	transport := &countingTransport{}
+	limiter := &recordingLimiter{}
+	var logs bytes.Buffer
+	var requestHooks, responseHooks []string
+	access, err := New(srv.URL+"/api/v4",
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithUserAgent("tests/1.0"),
+		WithHeader("X-Custom", "a"),
+		WithHeader("X-Custom", "b"),
+		WithLogger(zerolog.New(&logs)),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2}),
+		WithRateLimiter(limiter),
+		WithRequestHook(func(req *http.Request) {
+			requestHooks = append(requestHooks, req.URL.Path)
+		}),
+		WithResponseHook(func(req *http.Request, resp *http.Response, err error) {
+			responseHooks = append(responseHooks, resp.Status)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := access.Client("token").ProjectInfo(context.Background(), "g/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.PathWithNamespace != "g/p" {
+		t.Errorf("unexpected project %+v", project)
+	}
+
+	if transport.requests != 2 {
+		t.Errorf("got %d requests sent with the client, want 2", transport.requests)
+	}
+	if len(limiter.waits) != 2 || len(limiter.updates) != 2 || limiter.waits[0] != PrivateToken("token").Identity() {
+		t.Errorf("unexpected limiter calls %v and %v", limiter.waits, limiter.updates)
+	}
+	if strings.Join(requestHooks, ",") != "/api/v4/projects/g/p,/api/v4/projects/g/p" {
+		t.Errorf("unexpected request hook calls %v", requestHooks)
+	}
+	if strings.Join(responseHooks, ",") != "503 Service Unavailable,200 OK" {
+		t.Errorf("unexpected response hook calls %v", responseHooks)
+	}
+	if !strings.Contains(logs.String(), "retrying gitlab request") {
+		t.Errorf("the logger is not used, got logs %q", logs.String())
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"id":1,"path_with_namespace":"g/p"}`))
+	}))
+	defer srv.Close()
+
+	access := NewAPIAccess(nil, srv.URL, WithCache(NewMemoryCache(10)))
+	client := access.Client("token")
+	for i := 0; i < 3; i++ {
+		project, err := client.ProjectInfo(context.Background(), "g/p")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.PathWithNamespace != "g/p" {
+			t.Errorf("request %d: unexpected project %+v", i, project)
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("got %d requests and %d not modified responses, want 3 and 2", requests, notModified)
+	}
+
+	// responses depend on permissions, so they are not shared between users
+	if _, err := access.Client("other").ProjectInfo(context.Background(), "g/p"); err != nil {
+		t.Fatal(err)
+	}
+	if notModified != 2 {
+		t.Error("cached response of another user is revalidated")
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache(2)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, &CachedResponse{ETag: key})
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Error("the least recently used item is not evicted")
+	}
+
+	// reading makes the item recently used
+	if item, ok := cache.Get("b"); !ok || item.ETag != "b" {
+		t.Fatalf("unexpected item %v", item)
+	}
+	cache.Set("d", &CachedResponse{ETag: "d"})
+	if _, ok := cache.Get("c"); ok {
+		t.Error("c must be evicted instead of recently read b")
+	}
+	for _, key := range []string{"b", "d"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("%s is evicted", key)
+		}
+	}
+
+	// the item is replaced without eviction of others
+	cache.Set("b", &CachedResponse{ETag: "b2"})
+	if item, ok := cache.Get("b"); !ok || item.ETag != "b2" {
+		t.Errorf("unexpected item %v", item)
+	}
+	if _, ok := cache.Get("d"); !ok {
+		t.Error("d is evicted on replacement of b")
+	}
+
+	disabled := NewMemoryCache(0)
+	disabled.Set("a", &CachedResponse{})
+	if _, ok := disabled.Get("a"); ok {
+		t.Error("cache of zero capacity keeps items")
+	}
+}
@@ -0,0 +1,156 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+const (
+	// defaultPerPage is a page size used when it was not set explicitly. GitLab does not allow more than 100 items per page
+	defaultPerPage = 100
+	maxPerPage     = 100
+)
+
+// pager walks through pages of a list request. Offset based pagination is driven by X-Next-Page, X-Page and
+// X-Total-Pages headers, keyset based one is driven by the Link header
+type pager struct {
+	access *apiAccess
+	auth   Authenticator
+	path   string
+	keys   map[string]string
+
+	page    int    // number of the next page to request with offset based pagination
+	nextURL string // full URL of the next page with keyset based pagination
+	done    bool
+
+	limit int // maximum amount of items to get, zero means no limit
+	seen  int
+}
+
+// newPager creates a pager for the given list request. opts can be nil
+func (a *apiAccess) newPager(path string, auth Authenticator, keys map[string]string, opts *gitlabdata.ListOptions) *pager {
+	var options gitlabdata.ListOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Page <= 0 {
+		options.Page = 1
+	}
+	if options.PerPage <= 0 {
+		options.PerPage = defaultPerPage
+	}
+	if options.PerPage > maxPerPage {
+		options.PerPage = maxPerPage
+	}
+	if options.MaxItems > 0 && options.MaxItems < options.PerPage {
+		options.PerPage = options.MaxItems
+	}
+
+	pageKeys := make(map[string]string, len(keys)+1)
+	for key, value := range keys {
+		pageKeys[key] = value
+	}
+	pageKeys["per_page"] = strconv.Itoa(options.PerPage)
+
+	return &pager{
+		access: a,
+		auth:   auth,
+		path:   path,
+		keys:   pageKeys,
+		page:   options.Page,
+		limit:  options.MaxItems,
+	}
+}
+
+// nextPage requests the next page. Returns nil response when there are no pages left. It is up to the caller to close
+// the body of the response
+func (p *pager) nextPage(ctx context.Context) (*http.Response, error) {
+	if p.done || (p.limit > 0 && p.seen >= p.limit) {
+		return nil, nil
+	}
+
+	var resp *http.Response
+	var err error
+	if len(p.nextURL) > 0 {
+		var req *http.Request
+		req, err = http.NewRequest(http.MethodGet, p.nextURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create req to gitlab API: %s", err)
+		}
+		resp, err = p.access.send(ctx, req, p.auth)
+	} else {
+		p.keys["page"] = strconv.Itoa(p.page)
+		resp, err = p.access.makeRequest(ctx, p.path, p.auth, p.keys)
+	}
+	if err != nil {
+		p.done = true
+		return nil, err
+	}
+
+	p.setNext(resp.Header)
+	return resp, nil
+}
+
+// setNext computes where the next page is using response headers
+func (p *pager) setNext(header http.Header) {
+	p.nextURL = ""
+
+	if next := header.Get("X-Next-Page"); len(next) > 0 {
+		page, err := strconv.Atoi(next)
+		if err == nil {
+			p.page = page
+			return
+		}
+	}
+
+	if next := nextLink(header.Get("Link")); len(next) > 0 && len(header.Get("X-Next-Page")) == 0 && len(header.Get("X-Page")) == 0 {
+		// keyset pagination, there are no page numbers
+		p.nextURL = next
+		return
+	}
+
+	// GitLab omits X-Next-Page and X-Total-Pages for very large collections, rely on them only when they are present
+	page, pageErr := strconv.Atoi(header.Get("X-Page"))
+	total, totalErr := strconv.Atoi(header.Get("X-Total-Pages"))
+	if pageErr == nil && totalErr == nil && page < total {
+		p.page = page + 1
+		return
+	}
+
+	p.done = true
+}
+
+// take registers n items got from the last page and returns how many of them are to be kept to not exceed the limit
+func (p *pager) take(n int) int {
+	if p.limit > 0 && p.seen+n > p.limit {
+		n = p.limit - p.seen
+	}
+	p.seen += n
+	return n
+}
+
+// nextLink extracts the URL with rel="next" from the Link header value
+func nextLink(link string) string {
+	for _, item := range strings.Split(link, ",") {
+		parts := strings.Split(item, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if param == `rel="next"` || param == "rel=next" {
+				return target[1 : len(target)-1]
+			}
+		}
+	}
+	return ""
+}
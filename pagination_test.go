@@ -0,0 +1,162 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// pagedServer serves total tags split into pages, headers sets pagination headers of a page
+func pagedServer(t *testing.T, total int, headers func(w http.ResponseWriter, r *http.Request, page, pages int)) (*httptest.Server, *[]string) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+
+		perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if err != nil {
+			t.Errorf("invalid per_page in %s", r.URL.RawQuery)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		page := 1
+		if value := r.URL.Query().Get("page"); len(value) > 0 {
+			page, _ = strconv.Atoi(value)
+		}
+		if cursor := r.URL.Query().Get("cursor"); len(cursor) > 0 {
+			page, _ = strconv.Atoi(cursor)
+		}
+		pages := (total + perPage - 1) / perPage
+
+		headers(w, r, page, pages)
+		w.Write([]byte("["))
+		for i := (page - 1) * perPage; i < page*perPage && i < total; i++ {
+			if i > (page-1)*perPage {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `{"name":"t%d"}`, i)
+		}
+		w.Write([]byte("]"))
+	}))
+	return srv, &requests
+}
+
+func fetchTags(t *testing.T, srv *httptest.Server, opts *gitlabdata.ListOptions) []*gitlabdata.Tag {
+	t.Helper()
+	tags, err := NewAPIAccess(nil, srv.URL).Client("token").Tags(context.Background(), "g/p", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tags
+}
+
+func checkTags(t *testing.T, tags []*gitlabdata.Tag, expected int) {
+	t.Helper()
+	if len(tags) != expected {
+		t.Fatalf("got %d tags, expected %d", len(tags), expected)
+	}
+	for i, tag := range tags {
+		if tag.Name != fmt.Sprintf("t%d", i) {
+			t.Fatalf("tag %d is %s", i, tag.Name)
+		}
+	}
+}
+
+func TestPagerNextPage(t *testing.T) {
+	srv, requests := pagedServer(t, 25, func(w http.ResponseWriter, r *http.Request, page, pages int) {
+		w.Header().Set("X-Page", strconv.Itoa(page))
+		w.Header().Set("X-Total-Pages", strconv.Itoa(pages))
+		if page < pages {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		} else {
+			w.Header().Set("X-Next-Page", "")
+		}
+	})
+	defer srv.Close()
+
+	checkTags(t, fetchTags(t, srv, &gitlabdata.ListOptions{PerPage: 10}), 25)
+	if len(*requests) != 3 {
+		t.Errorf("expected 3 requests, got %v", *requests)
+	}
+}
+
+func TestPagerTotalPages(t *testing.T) {
+	srv, requests := pagedServer(t, 25, func(w http.ResponseWriter, r *http.Request, page, pages int) {
+		w.Header().Set("X-Page", strconv.Itoa(page))
+		w.Header().Set("X-Total-Pages", strconv.Itoa(pages))
+	})
+	defer srv.Close()
+
+	checkTags(t, fetchTags(t, srv, &gitlabdata.ListOptions{PerPage: 10}), 25)
+	if len(*requests) != 3 {
+		t.Errorf("expected 3 requests, got %v", *requests)
+	}
+}
+
+func TestPagerKeyset(t *testing.T) {
+	var srv *httptest.Server
+	srv, requests := pagedServer(t, 25, func(w http.ResponseWriter, r *http.Request, page, pages int) {
+		if page < pages {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/projects/g%%2Fp/repository/tags?cursor=%d&per_page=10>; rel="next"`, srv.URL, page+1))
+		}
+	})
+	defer srv.Close()
+
+	checkTags(t, fetchTags(t, srv, &gitlabdata.ListOptions{PerPage: 10}), 25)
+	if len(*requests) != 3 {
+		t.Errorf("expected 3 requests, got %v", *requests)
+	}
+}
+
+func TestPagerWithoutTotals(t *testing.T) {
+	// GitLab omits X-Total and X-Total-Pages for collections with more than 10000 items
+	srv, requests := pagedServer(t, 25, func(w http.ResponseWriter, r *http.Request, page, pages int) {
+		w.Header().Set("X-Page", strconv.Itoa(page))
+		if page < pages {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+	})
+	defer srv.Close()
+
+	checkTags(t, fetchTags(t, srv, &gitlabdata.ListOptions{PerPage: 10}), 25)
+	if len(*requests) != 3 {
+		t.Errorf("expected 3 requests, got %v", *requests)
+	}
+}
+
+func TestPagerMaxItems(t *testing.T) {
+	srv, requests := pagedServer(t, 25, func(w http.ResponseWriter, r *http.Request, page, pages int) {
+		if page < pages {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+	})
+	defer srv.Close()
+
+	checkTags(t, fetchTags(t, srv, &gitlabdata.ListOptions{PerPage: 10, MaxItems: 13}), 13)
+	if len(*requests) != 2 {
+		t.Errorf("expected 2 requests, got %v", *requests)
+	}
+
+	*requests = nil
+	checkTags(t, fetchTags(t, srv, &gitlabdata.ListOptions{MaxItems: 5}), 5)
+	if len(*requests) != 1 || (*requests)[0] != "page=1&per_page=5" {
+		t.Errorf("expected a single request for 5 items, got %v", *requests)
+	}
+}
+
+func TestPagerPerPageClamp(t *testing.T) {
+	srv, requests := pagedServer(t, 5, func(w http.ResponseWriter, r *http.Request, page, pages int) {})
+	defer srv.Close()
+
+	checkTags(t, fetchTags(t, srv, &gitlabdata.ListOptions{PerPage: 1000}), 5)
+	checkTags(t, fetchTags(t, srv, nil), 5)
+	for _, query := range *requests {
+		if query != "page=1&per_page=100" {
+			t.Errorf("unexpected query %s", query)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+const (
+	pseudoVersionTimeFormat = "20060102150405"
+	pseudoVersionRevLength  = 12
+)
+
+// PseudoVersion returns a Go pseudo-version of the given ref (branch, tag or commit SHA) of a module located in the
+// given subdirectory of the project, empty modulePathPrefix stands for the root one. The highest version tagged
+// in the ref history is used as a base, see FormatPseudoVersion. Tagged versions are checked highest first with
+// a merge base request each, so the cost depends on the number of newer tags outside of the history rather than
+// on the history length
+func (r *VersionResolver) PseudoVersion(ctx context.Context, project, modulePathPrefix, ref string) (string, error) {
+	head, err := r.client.Commit(ctx, project, ref)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := r.Versions(ctx, project, modulePathPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	var base *Version
+	checked := map[string]bool{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		// build metadata is not allowed in pseudo-versions
+		if len(v.Build) > 0 || v.Tag.Commit == nil {
+			continue
+		}
+
+		id := v.Tag.Commit.ID
+		ancestor, ok := checked[id]
+		if !ok {
+			ancestor, err = commitIsAncestor(ctx, r.client, project, id, head.ID)
+			if err != nil {
+				return "", err
+			}
+			checked[id] = ancestor
+		}
+		if ancestor {
+			base = v
+			break
+		}
+	}
+
+	return FormatPseudoVersion(base, head)
+}
+
+// FormatPseudoVersion builds a pseudo-version of the commit based on the given version, which can be nil if there is
+// no base. Pseudo-version forms are:
+//
+//	v0.0.0-20190102150405-abcdef123456 - there is no base version
+//	v1.2.4-0.20190102150405-abcdef123456 - base version is a release v1.2.3
+//	v1.2.3-pre.0.20190102150405-abcdef123456 - base version is a pre-release v1.2.3-pre
+//
+// Commit time is taken from CommittedDate
+func FormatPseudoVersion(base *Version, commit *gitlabdata.Commit) (string, error) {
+	if commit.CommittedDate == nil {
+		return "", fmt.Errorf("commit %s has no commit date", commit.ID)
+	}
+	if len(commit.ID) < pseudoVersionRevLength {
+		return "", fmt.Errorf("commit id %s is too short", commit.ID)
+	}
+	suffix := commit.CommittedDate.UTC().Format(pseudoVersionTimeFormat) + "-" + commit.ID[:pseudoVersionRevLength]
+
+	switch {
+	case base == nil:
+		return "v0.0.0-" + suffix, nil
+	case base.IsPrerelease():
+		return fmt.Sprintf("v%d.%d.%d-%s.0.%s", base.Major, base.Minor, base.Patch, base.Prerelease, suffix), nil
+	default:
+		return fmt.Sprintf("v%d.%d.%d-0.%s", base.Major, base.Minor, base.Patch+1, suffix), nil
+	}
+}
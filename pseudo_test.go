@@ -0,0 +1,148 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// pseudoServer serves a repository with two branches forked at the commit "a":
+//
+//	a - b - c       master, tags v1.0.0 at a and v1.1.0 at b
+//	     \
+//	      x - y     feature, tags v2.0.0 at x and v1.5.0 at y
+//
+// requests counts requests by gitlab API route
+func pseudoServer(t *testing.T, requests map[string]int) *httptest.Server {
+	parents := map[string]string{"b": "a", "c": "b", "x": "a", "y": "x"}
+	heads := map[string]string{"master": "c", "feature": "y"}
+	tags := map[string]string{"v1.0.0": "a", "v1.1.0": "b", "v2.0.0": "x", "v1.5.0": "y", "v1.6.0+build": "c"}
+	sha := func(name string) string {
+		return strings.Repeat(name, 40)
+	}
+	commit := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":             sha(name),
+			"committed_date": "2020-01-02T06:04:05+03:00",
+		}
+	}
+	history := func(name string) []string {
+		var res []string
+		for ; len(name) > 0; name = parents[name] {
+			res = append(res, sha(name))
+		}
+		return res
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := strings.TrimPrefix(r.URL.EscapedPath(), "/projects/g%2Fp/repository/")
+		switch {
+		case route == "tags":
+			requests["tags"]++
+			var res []interface{}
+			for name, target := range tags {
+				res = append(res, map[string]interface{}{"name": name, "commit": commit(target)})
+			}
+			json.NewEncoder(w).Encode(res)
+		case strings.HasPrefix(route, "commits/"):
+			requests["commit"]++
+			name := strings.TrimPrefix(route, "commits/")
+			if head, ok := heads[name]; ok {
+				name = head
+			}
+			json.NewEncoder(w).Encode(commit(name[:1]))
+		case route == "merge_base":
+			requests["merge_base"]++
+			refs := r.URL.Query()["refs[]"]
+			ancestors := map[string]bool{}
+			for _, id := range history(refs[1][:1]) {
+				ancestors[id] = true
+			}
+			for _, id := range history(refs[0][:1]) {
+				if ancestors[id] {
+					json.NewEncoder(w).Encode(commit(id[:1]))
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPseudoVersion(t *testing.T) {
+	tests := []struct {
+		ref        string
+		want       string
+		mergeBases int
+	}{
+		// v1.6.0+build is skipped, v1.5.0 and v2.0.0 are not in the history
+		{ref: "master", want: "v1.1.1-0.20200102030405-cccccccccccc", mergeBases: 3},
+		{ref: "feature", want: "v2.0.1-0.20200102030405-yyyyyyyyyyyy", mergeBases: 1},
+		{ref: "a", want: "v1.0.1-0.20200102030405-aaaaaaaaaaaa", mergeBases: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			requests := map[string]int{}
+			srv := pseudoServer(t, requests)
+			resolver := NewVersionResolver(NewAPIAccess(nil, srv.URL).Client("token"))
+
+			got, err := resolver.PseudoVersion(context.Background(), "g/p", "", tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got pseudo-version %s, want %s", got, tt.want)
+			}
+			if requests["merge_base"] != tt.mergeBases {
+				t.Errorf("got %d merge base requests, want %d", requests["merge_base"], tt.mergeBases)
+			}
+		})
+	}
+}
+
+func TestFormatPseudoVersion(t *testing.T) {
+	moment := time.Date(2019, 1, 2, 18, 4, 5, 0, time.FixedZone("", 3*3600))
+	commit := &gitlabdata.Commit{
+		ID:            "abcdef1234567890abcdef1234567890abcdef12",
+		CommittedDate: &gitlabdata.Timestamp{Time: moment},
+	}
+
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"", "v0.0.0-20190102150405-abcdef123456"},
+		{"v1.2.3", "v1.2.4-0.20190102150405-abcdef123456"},
+		{"v1.2.3-pre", "v1.2.3-pre.0.20190102150405-abcdef123456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			var base *Version
+			if len(tt.base) > 0 {
+				base = mustParseVersion(t, tt.base)
+			}
+			got, err := FormatPseudoVersion(base, commit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FormatPseudoVersion(nil, &gitlabdata.Commit{ID: commit.ID}); err == nil {
+		t.Error("commits without dates must be rejected")
+	}
+}
@@ -0,0 +1,205 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter keeps the rate of requests to gitlab instance under control. Implementations must be safe for
+// concurrent use as they are shared between all clients spawned by APIAccess
+type RateLimiter interface {
+	// Wait blocks until a request on behalf of the user is allowed or the context is done. The user is
+	// identified by Authenticator.Identity
+	Wait(ctx context.Context, identity string) error
+
+	// Update feeds the limiter with headers of a response got on behalf of the user
+	Update(identity string, header http.Header)
+}
+
+// RateLimit describes a budget of requests
+type RateLimit struct {
+	// Requests is an amount of requests allowed per Period, zero means there is no limit
+	Requests int
+	Period   time.Duration
+
+	// Burst is a maximum number of requests which can be made at once, Requests is used if it is zero
+	Burst int
+}
+
+// NewRateLimiter creates a rate limiter with a budget shared by all users and a budget for each user.
+// Besides these budgets it follows RateLimit-Remaining and RateLimit-Reset headers of gitlab responses:
+// requests on behalf of a user are suspended till the reset moment when the remaining count hits zero
+func NewRateLimiter(global, perToken RateLimit) RateLimiter {
+	return &rateLimiter{
+		global:   newBucket(global),
+		perToken: perToken,
+		tokens:   map[[sha256.Size]byte]*tokenLimit{},
+		now:      time.Now,
+		sleep:    sleep,
+	}
+}
+
+// idleTimeout is how long a state of a user who makes no requests is kept. States are only dropped when they are
+// indistinguishable from new ones, i.e. the bucket is full and the user is not blocked
+const idleTimeout = 10 * time.Minute
+
+type rateLimiter struct {
+	lock      sync.Mutex
+	global    *bucket
+	perToken  RateLimit
+	tokens    map[[sha256.Size]byte]*tokenLimit // keyed by identity hashes, identities may contain tokens
+	lastSweep time.Time
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// tokenLimit is a state of a single user
+type tokenLimit struct {
+	bucket       *bucket
+	blockedUntil time.Time
+	lastUsed     time.Time
+}
+
+func (l *rateLimiter) user(identity string, now time.Time) *tokenLimit {
+	key := hashIdentity(identity)
+	res, ok := l.tokens[key]
+	if !ok {
+		res = &tokenLimit{
+			bucket: newBucket(l.perToken),
+		}
+		l.tokens[key] = res
+	}
+	res.lastUsed = now
+	return res
+}
+
+// hashIdentity turns an identity into a key of a user state
+func hashIdentity(identity string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(identity))
+}
+
+// sweep drops states of idle users, it runs not more often than once per idleTimeout
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	l.lastSweep = now
+
+	for key, user := range l.tokens {
+		if now.Sub(user.lastUsed) >= idleTimeout && !now.Before(user.blockedUntil) && user.bucket.full(now) {
+			delete(l.tokens, key)
+		}
+	}
+}
+
+func (l *rateLimiter) Wait(ctx context.Context, identity string) error {
+	l.lock.Lock()
+	now := l.now()
+	l.sweep(now)
+	user := l.user(identity, now)
+	delay := l.global.reserve(now)
+	if userDelay := user.bucket.reserve(now); userDelay > delay {
+		delay = userDelay
+	}
+	if blocked := user.blockedUntil.Sub(now); blocked > delay {
+		delay = blocked
+	}
+	l.lock.Unlock()
+
+	if err := l.sleep(ctx, delay); err != nil {
+		l.lock.Lock()
+		l.global.cancel()
+		user.bucket.cancel()
+		l.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (l *rateLimiter) Update(identity string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	user := l.user(identity, l.now())
+	if until := time.Unix(reset, 0); until.After(user.blockedUntil) {
+		user.blockedUntil = until
+	}
+}
+
+// bucket is a token bucket, it is not safe for concurrent use
+type bucket struct {
+	rate   float64 // tokens per nanosecond, zero means no limit
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit RateLimit) *bucket {
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		return &bucket{}
+	}
+
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Requests
+	}
+	return &bucket{
+		rate:   float64(limit.Requests) / float64(limit.Period),
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// reserve takes a token out of the bucket and returns how long to wait until it can be used
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+
+	if !b.last.IsZero() {
+		b.tokens += float64(now.Sub(b.last)) * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate)
+}
+
+// full checks if the bucket would be full at the given moment
+func (b *bucket) full(now time.Time) bool {
+	if b.rate == 0 || b.last.IsZero() {
+		return true
+	}
+	return b.tokens+float64(now.Sub(b.last))*b.rate >= b.burst
+}
+
+// cancel returns a reserved token back
+func (b *bucket) cancel() {
+	if b.rate == 0 {
+		return
+	}
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
@@ -0,0 +1,185 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeClock stands for the wall clock of a limiter: time only goes when the test moves it and sleeps are recorded
+// instead of being made
+type fakeClock struct {
+	now    time.Time
+	delays []time.Duration
+}
+
+func newTestLimiter(global, perToken RateLimit) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(global, perToken).(*rateLimiter)
+	limiter.now = func() time.Time {
+		return clock.now
+	}
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		clock.delays = append(clock.delays, d)
+		return ctx.Err()
+	}
+	return limiter, clock
+}
+
+// wait makes a request on behalf of the user and returns the delay the limiter asked for
+func (c *fakeClock) wait(t *testing.T, limiter RateLimiter, identity string) time.Duration {
+	t.Helper()
+
+	if err := limiter.Wait(context.Background(), identity); err != nil {
+		t.Fatal(err)
+	}
+	return c.delays[len(c.delays)-1]
+}
+
+// sameDelay compares delays with a tolerance for floating point rounding of bucket rates
+func sameDelay(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Microsecond && diff < time.Microsecond
+}
+
+func TestRateLimiterGlobal(t *testing.T) {
+	limiter, clock := newTestLimiter(RateLimit{Requests: 10, Period: 100 * time.Millisecond}, RateLimit{})
+
+	// the burst of 10 requests passes at once, the rest ones queue up no matter whose requests they are
+	for i := 0; i < 30; i++ {
+		want := time.Duration(0)
+		if i >= 10 {
+			want = time.Duration(i-9) * 10 * time.Millisecond
+		}
+		if got := clock.wait(t, limiter, fmt.Sprintf("user-%d", i%3)); !sameDelay(got, want) {
+			t.Errorf("request %d: got delay %s, want %s", i, got, want)
+		}
+	}
+
+	// the budget is restored over time, but not above the burst
+	clock.now = clock.now.Add(time.Second)
+	for i := 0; i < 10; i++ {
+		if got := clock.wait(t, limiter, "user-0"); got != 0 {
+			t.Errorf("request %d of the restored burst: got delay %s", i, got)
+		}
+	}
+	if got := clock.wait(t, limiter, "user-0"); !sameDelay(got, 10*time.Millisecond) {
+		t.Errorf("got delay %s after the restored burst, want 10ms", got)
+	}
+}
+
+func TestRateLimiterPerToken(t *testing.T) {
+	limiter, clock := newTestLimiter(RateLimit{}, RateLimit{Requests: 5, Period: 100 * time.Millisecond})
+
+	// every user has its own budget, so users do not wait for each other
+	for i := 0; i < 10; i++ {
+		want := time.Duration(0)
+		if i >= 5 {
+			want = time.Duration(i-4) * 20 * time.Millisecond
+		}
+		for user := 0; user < 4; user++ {
+			if got := clock.wait(t, limiter, fmt.Sprintf("user-%d", user)); !sameDelay(got, want) {
+				t.Errorf("request %d of user-%d: got delay %s, want %s", i, user, got, want)
+			}
+		}
+	}
+}
+
+func TestRateLimiterBlocked(t *testing.T) {
+	limiter, clock := newTestLimiter(RateLimit{}, RateLimit{})
+	limiter.Update("blocked", map[string][]string{
+		"Ratelimit-Remaining": {"0"},
+		"Ratelimit-Reset":     {fmt.Sprint(clock.now.Add(time.Hour).Unix())},
+	})
+
+	for i := 0; i < 10; i++ {
+		if got := clock.wait(t, limiter, "free"); got != 0 {
+			t.Errorf("requests of another user were suspended for %s", got)
+		}
+	}
+	if got := clock.wait(t, limiter, "blocked"); got != time.Hour {
+		t.Errorf("requests of the user who hit the limit were suspended for %s, want 1h", got)
+	}
+
+	// the user is free after the reset moment
+	clock.now = clock.now.Add(time.Hour)
+	if got := clock.wait(t, limiter, "blocked"); got != 0 {
+		t.Errorf("got delay %s after the reset", got)
+	}
+
+	// the remaining budget is not a reason to block
+	limiter.Update("free", map[string][]string{
+		"Ratelimit-Remaining": {"1"},
+		"Ratelimit-Reset":     {fmt.Sprint(clock.now.Add(time.Hour).Unix())},
+	})
+	if got := clock.wait(t, limiter, "free"); got != 0 {
+		t.Errorf("got delay %s with requests remaining", got)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter, clock := newTestLimiter(RateLimit{Requests: 1, Period: time.Minute}, RateLimit{Requests: 1, Period: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, "user"); err != context.Canceled {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+
+	// the canceled request gives its reservation back
+	if got := clock.wait(t, limiter, "user"); got != 0 {
+		t.Errorf("got delay %s after the canceled request", got)
+	}
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	limiter, _ := newTestLimiter(RateLimit{}, RateLimit{Requests: 1, Period: time.Minute})
+	waiting := PrivateToken("glpat-waiting").Identity()
+	updated := PrivateToken("glpat-updated").Identity()
+	if err := limiter.Wait(context.Background(), waiting); err != nil {
+		t.Fatal(err)
+	}
+	limiter.Update(updated, map[string][]string{
+		"Ratelimit-Remaining": {"0"},
+		"Ratelimit-Reset":     {"0"},
+	})
+
+	// identities contain tokens, so states are keyed by their hashes
+	if len(limiter.tokens) != 2 {
+		t.Fatalf("got %d states, want 2", len(limiter.tokens))
+	}
+	for _, identity := range []string{waiting, updated} {
+		if _, ok := limiter.tokens[sha256.Sum256([]byte(identity))]; !ok {
+			t.Errorf("no state keyed by the hash of %s", identity)
+		}
+	}
+	for key := range limiter.tokens {
+		for _, token := range []string{"glpat-waiting", "glpat-updated"} {
+			if bytes.Contains(key[:], []byte(token)) {
+				t.Errorf("raw token %s is used in a key", token)
+			}
+		}
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	limiter, clock := newTestLimiter(RateLimit{}, RateLimit{Requests: 1, Period: time.Minute})
+	clock.wait(t, limiter, "idle")
+	clock.wait(t, limiter, "active")
+	limiter.Update("blocked", map[string][]string{
+		"Ratelimit-Remaining": {"0"},
+		"Ratelimit-Reset":     {fmt.Sprint(clock.now.Add(time.Hour).Unix())},
+	})
+
+	clock.now = clock.now.Add(idleTimeout + time.Minute)
+	clock.wait(t, limiter, "active")
+	if len(limiter.tokens) != 2 {
+		t.Fatalf("expected states of active and blocked users to be kept, got %d states", len(limiter.tokens))
+	}
+	if _, ok := limiter.tokens[hashIdentity("idle")]; ok {
+		t.Error("state of the idle user must be dropped")
+	}
+}
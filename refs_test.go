@@ -0,0 +1,189 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// refsServer serves a repository with commits "a" - "b" on master and an unrelated root commit "c" on orphan,
+// requests counts merge base requests
+func refsServer(t *testing.T, requests *int) *httptest.Server {
+	parents := map[string]string{"b": "a"}
+	heads := map[string]string{"master": "b", "orphan": "c"}
+	resolve := func(ref string) string {
+		if head, ok := heads[ref]; ok {
+			return strings.Repeat(head, 40)
+		}
+		for _, name := range []string{"a", "b", "c"} {
+			if ref == strings.Repeat(name, 40) {
+				return ref
+			}
+		}
+		return ""
+	}
+	history := func(id string) map[string]bool {
+		res := map[string]bool{}
+		for name := id[:1]; len(name) > 0; name = parents[name] {
+			res[strings.Repeat(name, 40)] = true
+		}
+		return res
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := strings.TrimPrefix(r.URL.EscapedPath(), "/projects/g%2Fp/repository/")
+		switch {
+		case strings.HasPrefix(route, "commits/"):
+			id := resolve(strings.TrimPrefix(route, "commits/"))
+			if len(id) == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": id})
+		case route == "merge_base":
+			*requests++
+			refs := r.URL.Query()["refs[]"]
+			if len(refs) != 2 {
+				t.Errorf("unexpected merge base request %s", r.URL)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			first, second := resolve(refs[0]), resolve(refs[1])
+			if len(first) == 0 || len(second) == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			ancestors := history(second)
+			for id := range history(first) {
+				if ancestors[id] {
+					json.NewEncoder(w).Encode(map[string]interface{}{"id": id})
+					return
+				}
+			}
+			http.NotFound(w, r)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIsAncestor(t *testing.T) {
+	a, b, c := strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40)
+	tests := []struct {
+		ancestor   string
+		descendant string
+		want       bool
+		mergeBases int
+	}{
+		{ancestor: a, descendant: "master", want: true, mergeBases: 1},
+		{ancestor: "master", descendant: a, want: false, mergeBases: 1},
+		{ancestor: b, descendant: "master", want: true, mergeBases: 0},
+		{ancestor: a, descendant: "orphan", want: false, mergeBases: 1},
+		{ancestor: "orphan", descendant: c, want: true, mergeBases: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ancestor[:1]+"-"+tt.descendant, func(t *testing.T) {
+			var requests int
+			client := NewAPIAccess(nil, refsServer(t, &requests).URL).Client("token")
+			got, err := client.IsAncestor(context.Background(), "g/p", tt.ancestor, tt.descendant)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+			if requests != tt.mergeBases {
+				t.Errorf("got %d merge base requests, want %d", requests, tt.mergeBases)
+			}
+		})
+	}
+}
+
+func TestIsAncestorMissingRef(t *testing.T) {
+	var requests int
+	client := NewAPIAccess(nil, refsServer(t, &requests).URL).Client("token")
+
+	for _, refs := range [][2]string{{"missing", "master"}, {"master", "missing"}} {
+		_, err := client.IsAncestor(context.Background(), "g/p", refs[0], refs[1])
+		if !IsNotFound(err) {
+			t.Errorf("%s is ancestor of %s: got error %v, expected not found", refs[0], refs[1], err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("merge base must not be requested for missing refs, got %d requests", requests)
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	id := strings.Repeat("a", 40)
+	commit := `{"id":"` + id + `"}`
+	routes := map[string]string{
+		"GET /projects/g%2Fp/repository/commits/v1.0.0":      commit,
+		"GET /projects/g%2Fp/repository/commits/release%2F1": commit,
+		"GET /projects/g%2Fp/repository/commits/aaaaaaa":     commit,
+		"GET /projects/g%2Fp/repository/commits/" + id + "/refs": `[
+			{"type":"branch","name":"v1.0.0"},
+			{"type":"branch","name":"release/1"},
+			{"type":"tag","name":"v1.0.0"}
+		]`,
+	}
+
+	tests := []struct {
+		ref  string
+		kind RefKind
+	}{
+		{"v1.0.0", RefKindTag},
+		{"release/1", RefKindBranch},
+		{"aaaaaaa", RefKindCommit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			srv, requests := apiServer(t, routes)
+			client := NewAPIAccess(nil, srv.URL).Client("token")
+
+			sha, kind, err := client.ResolveRef(context.Background(), "g/p", tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sha != id || kind != tt.kind {
+				t.Errorf("got %s %s, want %s %s", kind, sha, tt.kind, id)
+			}
+			if len(*requests) != 2 {
+				t.Fatalf("got %d requests", len(*requests))
+			}
+			checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/commits/"+url.PathEscape(tt.ref), url.Values{"stats": {"true"}})
+			checkRequest(t, (*requests)[1], http.MethodGet, "/projects/g%2Fp/repository/commits/"+id+"/refs", url.Values{"type": {"all"}})
+		})
+	}
+
+	srv, _ := apiServer(t, routes)
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+	if _, _, err := client.ResolveRef(context.Background(), "g/p", "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
+
+func TestMergeBase(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/merge_base": `{"id":"aaa","title":"base"}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	base, err := client.MergeBase(context.Background(), "g/p", "master", "feature/x", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.ID != "aaa" || base.Title != "base" {
+		t.Errorf("unexpected merge base %+v", base)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/merge_base",
+		url.Values{"refs[]": {"master", "feature/x", "v1.0.0"}})
+}
@@ -0,0 +1,153 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+const testRelease = `{
+	"tag_name": "v1.0.0",
+	"name": "First release",
+	"description": "notes",
+	"released_at": "2020-01-02T03:04:05.000Z",
+	"commit": {"id": "aaa"},
+	"assets": {"count": 1, "links": [{"id": 1, "name": "binary", "url": "https://example.com/bin", "link_type": "package"}]}
+}`
+
+// checkRelease checks the release was decoded out of testRelease
+func checkRelease(t *testing.T, release *gitlabdata.Release) {
+	t.Helper()
+
+	if release.TagName != "v1.0.0" || release.Name != "First release" || release.Description != "notes" || release.Commit.ID != "aaa" {
+		t.Errorf("unexpected release %+v", release)
+	}
+	if release.ReleasedAt == nil || !release.ReleasedAt.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("got release date %v", release.ReleasedAt)
+	}
+	if assets := release.Assets; assets == nil || len(assets.Links) != 1 || assets.Links[0].LinkType != gitlabdata.PackageLinkType {
+		t.Errorf("unexpected assets %+v", assets)
+	}
+}
+
+func TestReleases(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/releases":                "[" + testRelease + `,{"tag_name":"v0.1.0","upcoming_release":true}]`,
+		"GET /projects/g%2Fp/releases/release%2Fv1.0": testRelease,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	releases, err := client.Releases(context.Background(), "g/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 2 {
+		t.Fatalf("got %d releases", len(releases))
+	}
+	checkRelease(t, releases[0])
+	if releases[1].TagName != "v0.1.0" || !releases[1].UpcomingRelease || releases[1].ReleasedAt != nil {
+		t.Errorf("unexpected release %+v", releases[1])
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/releases", url.Values{})
+
+	release, err := client.Release(context.Background(), "g/p", "release/v1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRelease(t, release)
+	checkRequest(t, (*requests)[1], http.MethodGet, "/projects/g%2Fp/releases/release%2Fv1.0", url.Values{})
+
+	if _, err := client.Release(context.Background(), "g/p", "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
+
+func TestCreateRelease(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"POST /projects/g%2Fp/releases": testRelease,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	linkType := gitlabdata.PackageLinkType
+	release, err := client.CreateRelease(context.Background(), "g/p", &gitlabdata.CreateReleaseOptions{
+		Name:        gitlabdata.String("First release"),
+		TagName:     gitlabdata.String("v1.0.0"),
+		Description: gitlabdata.String("notes"),
+		Ref:         gitlabdata.String("master"),
+		Milestones:  []string{"m1"},
+		Assets: &gitlabdata.ReleaseAssetsOptions{
+			Links: []*gitlabdata.ReleaseLinkOptions{{
+				Name:     gitlabdata.String("binary"),
+				URL:      gitlabdata.String("https://example.com/bin"),
+				LinkType: &linkType,
+			}},
+		},
+		ReleasedAt: gitlabdata.Time(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRelease(t, release)
+
+	req := (*requests)[0]
+	checkRequest(t, req, http.MethodPost, "/projects/g%2Fp/releases", url.Values{})
+	want := map[string]interface{}{
+		"name":        "First release",
+		"tag_name":    "v1.0.0",
+		"description": "notes",
+		"ref":         "master",
+		"milestones":  []interface{}{"m1"},
+		"assets": map[string]interface{}{
+			"links": []interface{}{map[string]interface{}{
+				"name":      "binary",
+				"url":       "https://example.com/bin",
+				"link_type": "package",
+			}},
+		},
+		"released_at": "2020-01-02T03:04:05Z",
+	}
+	if !reflect.DeepEqual(req.body, want) {
+		t.Errorf("got body\n%v\nwant\n%v", req.body, want)
+	}
+}
+
+func TestUpdateRelease(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"PUT /projects/g%2Fp/releases/v1.0.0": testRelease,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	release, err := client.UpdateRelease(context.Background(), "g/p", "v1.0.0", &gitlabdata.UpdateReleaseOptions{
+		Description: gitlabdata.String("notes"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRelease(t, release)
+
+	// options which are not set are not sent
+	req := (*requests)[0]
+	checkRequest(t, req, http.MethodPut, "/projects/g%2Fp/releases/v1.0.0", url.Values{})
+	if want := map[string]interface{}{"description": "notes"}; !reflect.DeepEqual(req.body, want) {
+		t.Errorf("got body %v, want %v", req.body, want)
+	}
+}
+
+func TestDeleteRelease(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"DELETE /projects/g%2Fp/releases/v1.0.0": testRelease,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	release, err := client.DeleteRelease(context.Background(), "g/p", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRelease(t, release)
+	checkRequest(t, (*requests)[0], http.MethodDelete, "/projects/g%2Fp/releases/v1.0.0", url.Values{})
+}
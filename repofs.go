@@ -0,0 +1,425 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// RepoFSOption configures repository file system
+type RepoFSOption func(fsys *RepositoryFS)
+
+// WithFSCache keeps directory listings and file contents once they were retrieved, so that they are requested
+// from gitlab only once. It is only safe with immutable refs, i.e. tags and commit SHAs
+func WithFSCache() RepoFSOption {
+	return func(fsys *RepositoryFS) {
+		fsys.dirs = map[string][]*gitlabdata.TreeNode{}
+		fsys.files = map[string][]byte{}
+		fsys.sizes = map[string]int64{}
+	}
+}
+
+// WithFSContext sets a context for requests made by the file system, context.Background() is used by default
+func WithFSContext(ctx context.Context) RepoFSOption {
+	return func(fsys *RepositoryFS) {
+		fsys.ctx = ctx
+	}
+}
+
+// RepositoryFS is a read-only view of a repository at the given ref. It implements fs.FS, fs.ReadDirFS, fs.StatFS
+// and fs.ReadFileFS. Directories and files are requested lazily, when they are actually needed
+type RepositoryFS struct {
+	client  Client
+	project string
+	ref     string
+	ctx     context.Context
+
+	lock  sync.Mutex
+	dirs  map[string][]*gitlabdata.TreeNode // nil if caching is off
+	files map[string][]byte
+	sizes map[string]int64
+}
+
+var (
+	_ fs.FS         = &RepositoryFS{}
+	_ fs.ReadDirFS  = &RepositoryFS{}
+	_ fs.StatFS     = &RepositoryFS{}
+	_ fs.ReadFileFS = &RepositoryFS{}
+)
+
+// RepoFS creates a file system view of the project repository at the given ref (branch, tag or commit SHA).
+// The ref is required, file requests do not fall back to the default branch
+func RepoFS(client Client, project, ref string, opts ...RepoFSOption) *RepositoryFS {
+	fsys := &RepositoryFS{
+		client:  client,
+		project: project,
+		ref:     ref,
+		ctx:     context.Background(),
+	}
+	for _, opt := range opts {
+		opt(fsys)
+	}
+	return fsys
+}
+
+// Open opens a file or a directory with the given name
+func (fsys *RepositoryFS) Open(name string) (fs.File, error) {
+	node, err := fsys.node("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Type == gitlabdata.TreeNodeTree {
+		return &repoDir{fsys: fsys, node: node}, nil
+	}
+	return &repoFile{fsys: fsys, node: node}, nil
+}
+
+// ReadDir reads the named directory and returns a list of its entries sorted by name
+func (fsys *RepositoryFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := fsys.node("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.Type != gitlabdata.TreeNodeTree {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	nodes, err := fsys.list(node.Path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return fsys.entries(nodes), nil
+}
+
+// Stat returns a FileInfo describing the named file. File sizes are requested with blob metadata, file contents
+// are not downloaded
+func (fsys *RepositoryFS) Stat(name string) (fs.FileInfo, error) {
+	node, err := fsys.node("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.info(node)
+}
+
+// ReadFile reads the named file and returns its contents
+func (fsys *RepositoryFS) ReadFile(name string) ([]byte, error) {
+	node, err := fsys.node("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.Type == gitlabdata.TreeNodeTree {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	data, err := fsys.content(node.Path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+
+	// the caller is allowed to modify the result, cached content must be kept intact
+	res := make([]byte, len(data))
+	copy(res, data)
+	return res, nil
+}
+
+// node looks for a tree node with the given name listing its parent directory
+func (fsys *RepositoryFS) node(op, name string) (*gitlabdata.TreeNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &gitlabdata.TreeNode{
+			Name: ".",
+			Type: gitlabdata.TreeNodeTree,
+		}, nil
+	}
+
+	dir, base := path.Split(name)
+	nodes, err := fsys.list(path.Clean(dir))
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	for _, node := range nodes {
+		if node.Name == base {
+			return node, nil
+		}
+	}
+	return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// list returns nodes of the directory sorted by name
+func (fsys *RepositoryFS) list(dir string) ([]*gitlabdata.TreeNode, error) {
+	if dir == "." {
+		dir = ""
+	}
+
+	fsys.lock.Lock()
+	nodes, ok := fsys.dirs[dir]
+	fsys.lock.Unlock()
+	if ok {
+		return nodes, nil
+	}
+
+	nodes, err := fsys.client.Tree(fsys.ctx, fsys.project, dir, fsys.ref, false)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	if fsys.dirs != nil {
+		fsys.lock.Lock()
+		fsys.dirs[dir] = nodes
+		fsys.lock.Unlock()
+	}
+	return nodes, nil
+}
+
+func (fsys *RepositoryFS) content(name string) ([]byte, error) {
+	fsys.lock.Lock()
+	data, ok := fsys.files[name]
+	fsys.lock.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	data, err := fsys.client.File(fsys.ctx, fsys.project, name, fsys.ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys.files != nil {
+		fsys.lock.Lock()
+		fsys.files[name] = data
+		fsys.lock.Unlock()
+	}
+	return data, nil
+}
+
+func (fsys *RepositoryFS) info(node *gitlabdata.TreeNode) (fs.FileInfo, error) {
+	info := &repoFileInfo{node: node}
+	if node.Type != gitlabdata.TreeNodeBlob {
+		return info, nil
+	}
+
+	size, err := fsys.size(node)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: node.Path, Err: err}
+	}
+	info.size = size
+	return info, nil
+}
+
+// size returns a size of the file without downloading it, the size of already downloaded content is used if any
+func (fsys *RepositoryFS) size(node *gitlabdata.TreeNode) (int64, error) {
+	fsys.lock.Lock()
+	size, ok := fsys.sizes[node.Path]
+	if data, downloaded := fsys.files[node.Path]; downloaded {
+		size, ok = int64(len(data)), true
+	}
+	fsys.lock.Unlock()
+	if ok {
+		return size, nil
+	}
+
+	var meta *gitlabdata.FileInfo
+	var err error
+	if len(node.ID) > 0 {
+		meta, err = fsys.client.BlobMetadata(fsys.ctx, fsys.project, node.ID)
+	} else {
+		meta, err = fsys.client.FileMetadata(fsys.ctx, fsys.project, node.Path, fsys.ref)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if fsys.sizes != nil {
+		fsys.lock.Lock()
+		fsys.sizes[node.Path] = meta.Size
+		fsys.lock.Unlock()
+	}
+	return meta.Size, nil
+}
+
+func (fsys *RepositoryFS) entries(nodes []*gitlabdata.TreeNode) []fs.DirEntry {
+	res := make([]fs.DirEntry, len(nodes))
+	for i, node := range nodes {
+		res[i] = repoDirEntry{fsys: fsys, node: node}
+	}
+	return res
+}
+
+// nodeMode converts git file mode into fs.FileMode
+func nodeMode(node *gitlabdata.TreeNode) fs.FileMode {
+	switch {
+	case node.Type == gitlabdata.TreeNodeTree:
+		return fs.ModeDir | 0555
+	case node.Type == gitlabdata.TreeNodeCommit:
+		// submodules have no content in the repository
+		return fs.ModeIrregular
+	case node.Mode == "120000":
+		return fs.ModeSymlink | 0444
+	case node.Mode == "100755":
+		return 0555
+	default:
+		return 0444
+	}
+}
+
+type repoFileInfo struct {
+	node *gitlabdata.TreeNode
+	size int64
+}
+
+func (i *repoFileInfo) Name() string {
+	return i.node.Name
+}
+
+func (i *repoFileInfo) Size() int64 {
+	return i.size
+}
+
+func (i *repoFileInfo) Mode() fs.FileMode {
+	return nodeMode(i.node)
+}
+
+// ModTime returns zero time, commit times of individual files are not known
+func (i *repoFileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (i *repoFileInfo) IsDir() bool {
+	return i.node.Type == gitlabdata.TreeNodeTree
+}
+
+// Sys returns the underlying *gitlabdata.TreeNode
+func (i *repoFileInfo) Sys() interface{} {
+	return i.node
+}
+
+type repoDirEntry struct {
+	fsys *RepositoryFS
+	node *gitlabdata.TreeNode
+}
+
+func (e repoDirEntry) Name() string {
+	return e.node.Name
+}
+
+func (e repoDirEntry) IsDir() bool {
+	return e.node.Type == gitlabdata.TreeNodeTree
+}
+
+func (e repoDirEntry) Type() fs.FileMode {
+	return nodeMode(e.node).Type()
+}
+
+func (e repoDirEntry) Info() (fs.FileInfo, error) {
+	return e.fsys.info(e.node)
+}
+
+// repoFile is an opened file, its content is retrieved on the first read
+type repoFile struct {
+	fsys   *RepositoryFS
+	node   *gitlabdata.TreeNode
+	reader *bytes.Reader
+}
+
+func (f *repoFile) load() error {
+	if f.reader != nil {
+		return nil
+	}
+	data, err := f.fsys.content(f.node.Path)
+	if err != nil {
+		return &fs.PathError{Op: "read", Path: f.node.Path, Err: err}
+	}
+	f.reader = bytes.NewReader(data)
+	return nil
+}
+
+func (f *repoFile) Stat() (fs.FileInfo, error) {
+	if f.reader != nil {
+		return &repoFileInfo{node: f.node, size: f.reader.Size()}, nil
+	}
+	return f.fsys.info(f.node)
+}
+
+func (f *repoFile) Read(p []byte) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	return f.reader.Read(p)
+}
+
+func (f *repoFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *repoFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	return f.reader.ReadAt(p, off)
+}
+
+func (f *repoFile) Close() error {
+	return nil
+}
+
+// repoDir is an opened directory, its listing is retrieved on the first ReadDir
+type repoDir struct {
+	fsys    *RepositoryFS
+	node    *gitlabdata.TreeNode
+	entries []fs.DirEntry
+	loaded  bool
+}
+
+func (d *repoDir) Stat() (fs.FileInfo, error) {
+	return &repoFileInfo{node: d.node}, nil
+}
+
+func (d *repoDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.Path, Err: fs.ErrInvalid}
+}
+
+func (d *repoDir) Close() error {
+	return nil
+}
+
+func (d *repoDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		nodes, err := d.fsys.list(d.node.Path)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.node.Path, Err: err}
+		}
+		d.entries = d.fsys.entries(nodes)
+		d.loaded = true
+	}
+
+	if n <= 0 {
+		res := d.entries
+		d.entries = nil
+		return res, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	res := d.entries[:n]
+	d.entries = d.entries[n:]
+	return res, nil
+}
@@ -0,0 +1,181 @@
+package gitlab
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// repoServer serves tree, files and blobs of a repository with the given files. requests counts requests by kind
+func repoServer(t *testing.T, files map[string]string) (*httptest.Server, func(kind string) int) {
+	blobID := func(content string) string {
+		sum := sha1.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	var lock sync.Mutex
+	requests := map[string]int{}
+	count := func(kind string) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests[kind]++
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := strings.TrimPrefix(r.URL.EscapedPath(), "/projects/g%2Fp/repository/")
+		parts := strings.Split(route, "/")
+		switch {
+		case route == "tree":
+			count("tree")
+			dir := r.URL.Query().Get("path")
+			seen := map[string]bool{}
+			var nodes []map[string]string
+			for name, content := range files {
+				rel := name
+				if len(dir) > 0 {
+					if !strings.HasPrefix(name, dir+"/") {
+						continue
+					}
+					rel = strings.TrimPrefix(name, dir+"/")
+				}
+				child := strings.Split(rel, "/")[0]
+				if seen[child] {
+					continue
+				}
+				seen[child] = true
+				node := map[string]string{"name": child, "path": path.Join(dir, child), "type": "blob", "mode": "100644", "id": blobID(content)}
+				if child != rel {
+					node["type"], node["mode"], node["id"] = "tree", "040000", blobID(child)
+				} else if strings.HasSuffix(child, ".sh") {
+					node["mode"] = "100755"
+				}
+				nodes = append(nodes, node)
+			}
+			if len(nodes) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			sort.Slice(nodes, func(i, j int) bool {
+				return nodes[i]["name"] < nodes[j]["name"]
+			})
+			json.NewEncoder(w).Encode(nodes)
+		case len(parts) == 2 && parts[0] == "files":
+			count("file")
+			name, _ := url.PathUnescape(parts[1])
+			content, ok := files[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"encoding": "base64",
+				"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+			})
+		case len(parts) == 3 && parts[0] == "blobs" && parts[2] == "raw" && r.Method == http.MethodHead:
+			count("blob-metadata")
+			for _, content := range files {
+				if blobID(content) == parts[1] {
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func(kind string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return requests[kind]
+	}
+}
+
+var testRepoFiles = map[string]string{
+	"README.md":        "readme",
+	"go.mod":           "module example.com/g/p\n",
+	"cmd/tool/main.go": "package main\n",
+	"scripts/run.sh":   "#!/bin/sh\n",
+	"pkg/a.go":         "package pkg\n\nconst A = 1\n",
+	"pkg/b.go":         "package pkg\n",
+}
+
+func TestRepoFS(t *testing.T) {
+	srv, _ := repoServer(t, testRepoFiles)
+	fsys := RepoFS(NewAPIAccess(nil, srv.URL).Client("token"), "g/p", "v1.0.0")
+
+	var names []string
+	for name := range testRepoFiles {
+		names = append(names, name)
+	}
+	if err := fstest.TestFS(fsys, names...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepoFSStat(t *testing.T) {
+	srv, requests := repoServer(t, testRepoFiles)
+	fsys := RepoFS(NewAPIAccess(nil, srv.URL).Client("token"), "g/p", "v1.0.0", WithFSCache())
+
+	info, err := fs.Stat(fsys, "pkg/a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(testRepoFiles["pkg/a.go"])) || info.IsDir() || info.Mode() != 0444 {
+		t.Errorf("unexpected info of pkg/a.go: size %d, mode %s", info.Size(), info.Mode())
+	}
+
+	entries, err := fs.ReadDir(fsys, "scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(testRepoFiles["scripts/run.sh"])) || info.Mode() != 0555 {
+		t.Errorf("unexpected info of scripts/run.sh: size %d, mode %s", info.Size(), info.Mode())
+	}
+
+	file, err := fsys.Open("go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := file.Stat(); err != nil || info.Size() != int64(len(testRepoFiles["go.mod"])) {
+		t.Errorf("unexpected stat of opened go.mod: %v, %v", info, err)
+	}
+	file.Close()
+
+	if n := requests("file"); n != 0 {
+		t.Errorf("stat must not download files, got %d file requests", n)
+	}
+
+	// sizes are cached
+	before := requests("blob-metadata")
+	if _, err := fs.Stat(fsys, "pkg/a.go"); err != nil {
+		t.Fatal(err)
+	}
+	if requests("blob-metadata") != before {
+		t.Error("cached size was requested again")
+	}
+
+	if _, err := fs.Stat(fsys, "pkg/none.go"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
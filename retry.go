@@ -0,0 +1,240 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes when and how failed requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is a total number of attempts including the first one. Values below 2 turn retries off
+	MaxAttempts int
+
+	// BaseBackoff is a delay before the first retry, every next retry doubles it until MaxBackoff is reached
+	BaseBackoff time.Duration
+
+	// MaxBackoff limits computed delays
+	MaxBackoff time.Duration
+
+	// MaxHeaderDelay limits delays requested by gitlab via Retry-After or RateLimit-Reset headers,
+	// DefaultMaxHeaderDelay is used if it is zero. The request fails instead of being retried if gitlab asks
+	// to wait longer
+	MaxHeaderDelay time.Duration
+
+	// Jitter is a fraction of a computed delay in [0, 1] range which is randomized to spread retries
+	// of concurrent clients in time
+	Jitter float64
+
+	// RetryStatuses is a list of HTTP status codes to retry, DefaultRetryStatuses are used if it is empty
+	RetryStatuses []int
+
+	// RetryMethods is a list of HTTP methods to retry. Only idempotent methods are retried if it is empty
+	RetryMethods []string
+
+	// RetryError checks if a network error is to be retried. IsTemporaryNetError is used if it is nil
+	RetryError func(err error) bool
+}
+
+// DefaultRetryStatuses are HTTP status codes retried by default
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultMaxHeaderDelay is the longest delay requested by gitlab response headers which is waited for by default
+const DefaultMaxHeaderDelay = 5 * time.Minute
+
+// idempotentMethods are HTTP methods retried by default
+var idempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// DefaultRetryPolicy returns retry policy suitable for most cases: 4 attempts with delays growing from
+// 500ms to 30s
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		BaseBackoff: 500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// IsTemporaryNetError checks if the error is a network timeout, reset or refused connection or unexpectedly
+// closed connection which may disappear on retry. Certificate errors, unknown hosts, unsupported URL schemes and
+// other errors which are bound to repeat are not temporary
+func IsTemporaryNetError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var (
+		authorityErr   x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		certificateErr x509.CertificateInvalidError
+	)
+	if errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &certificateErr) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// *url.Error implements net.Error for any failure, so only timeouts are taken from it
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// methodAllowed checks if requests with the given method can be retried
+func (p *RetryPolicy) methodAllowed(method string) bool {
+	methods := p.RetryMethods
+	if len(methods) == 0 {
+		methods = idempotentMethods
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// statusAllowed checks if responses with the given status code are to be retried
+func (p *RetryPolicy) statusAllowed(code int) bool {
+	codes := p.RetryStatuses
+	if len(codes) == 0 {
+		codes = DefaultRetryStatuses
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// errorAllowed checks if the network error is to be retried
+func (p *RetryPolicy) errorAllowed(err error) bool {
+	if p.RetryError != nil {
+		return p.RetryError(err)
+	}
+	return IsTemporaryNetError(err)
+}
+
+// delay computes a delay before the given retry (starting from 1) of the request failed with the given response
+// status, status is 0 and header is nil for network errors. false is returned if gitlab asks to wait longer than
+// allowed
+func (p *RetryPolicy) delay(retry int, status int, header http.Header) (time.Duration, bool) {
+	if d, ok := headerDelay(status, header, time.Now()); ok {
+		limit := p.MaxHeaderDelay
+		if limit <= 0 {
+			limit = DefaultMaxHeaderDelay
+		}
+		return d, d <= limit
+	}
+
+	backoff := float64(p.BaseBackoff) * math.Pow(2, float64(retry-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		backoff -= backoff * jitter * randFloat()
+	}
+	return time.Duration(backoff), true
+}
+
+// headerDelay extracts a delay requested by gitlab with Retry-After or RateLimit-Reset headers. RateLimit-Reset
+// is only a moment when the rate limit window is over, so it is only used when the limit was actually hit
+func headerDelay(status int, header http.Header, now time.Time) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+
+	if value := header.Get("Retry-After"); len(value) > 0 {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if moment, err := http.ParseTime(value); err == nil {
+			return nonNegative(moment.Sub(now)), true
+		}
+	}
+
+	if status != http.StatusTooManyRequests && header.Get("RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	if value := header.Get("RateLimit-Reset"); len(value) > 0 {
+		if stamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return nonNegative(time.Unix(stamp, 0).Sub(now)), true
+		}
+	}
+
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+var (
+	rnd     = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rndLock sync.Mutex
+)
+
+func randFloat() float64 {
+	rndLock.Lock()
+	defer rndLock.Unlock()
+	return rnd.Float64()
+}
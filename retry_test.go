@@ -0,0 +1,332 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTemporaryNetError(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://gitlab.example.com/api/v4", Err: err}
+	}
+	opErr := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", wrap(timeoutError{}), true},
+		{"connection-reset", wrap(opErr(syscall.ECONNRESET)), true},
+		{"connection-refused", wrap(opErr(syscall.ECONNREFUSED)), true},
+		{"eof", wrap(io.EOF), true},
+		{"unexpected-eof", wrap(io.ErrUnexpectedEOF), true},
+		{"canceled", wrap(context.Canceled), false},
+		{"deadline", wrap(context.DeadlineExceeded), false},
+		{"unknown-authority", wrap(x509.UnknownAuthorityError{}), false},
+		{"hostname", wrap(x509.HostnameError{Host: "gitlab.example.com"}), false},
+		{"dns-not-found", wrap(&net.DNSError{Err: "no such host", Name: "gitlab.example.com", IsNotFound: true}), false},
+		{"dns-timeout", wrap(&net.DNSError{Err: "timeout", Name: "gitlab.example.com", IsTimeout: true}), true},
+		{"unsupported-scheme", wrap(errors.New(`unsupported protocol scheme "ftp"`)), false},
+		{"other", fmt.Errorf("failed: %w", errors.New("boom")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTemporaryNetError(tt.err); got != tt.want {
+				t.Errorf("IsTemporaryNetError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	now := time.Now()
+	reset := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).Unix(), 10)
+	}
+	policy := &RetryPolicy{
+		BaseBackoff: time.Second,
+		MaxBackoff:  4 * time.Second,
+	}
+
+	tests := []struct {
+		name   string
+		retry  int
+		status int
+		header http.Header
+		min    time.Duration
+		max    time.Duration
+		ok     bool
+	}{
+		{
+			name:  "backoff",
+			retry: 2,
+			min:   2 * time.Second,
+			max:   2 * time.Second,
+			ok:    true,
+		},
+		{
+			name:  "max-backoff",
+			retry: 10,
+			min:   4 * time.Second,
+			max:   4 * time.Second,
+			ok:    true,
+		},
+		{
+			name:   "retry-after",
+			retry:  1,
+			status: http.StatusServiceUnavailable,
+			header: http.Header{"Retry-After": {"7"}},
+			min:    7 * time.Second,
+			max:    7 * time.Second,
+			ok:     true,
+		},
+		{
+			name:   "reset-on-429",
+			retry:  1,
+			status: http.StatusTooManyRequests,
+			header: http.Header{"Ratelimit-Reset": {reset(time.Minute)}},
+			min:    58 * time.Second,
+			max:    time.Minute,
+			ok:     true,
+		},
+		{
+			name:   "reset-on-exhausted-limit",
+			retry:  1,
+			status: http.StatusServiceUnavailable,
+			header: http.Header{"Ratelimit-Remaining": {"0"}, "Ratelimit-Reset": {reset(time.Minute)}},
+			min:    58 * time.Second,
+			max:    time.Minute,
+			ok:     true,
+		},
+		{
+			name:   "reset-ignored",
+			retry:  1,
+			status: http.StatusBadGateway,
+			header: http.Header{"Ratelimit-Remaining": {"100"}, "Ratelimit-Reset": {reset(time.Minute)}},
+			min:    time.Second,
+			max:    time.Second,
+			ok:     true,
+		},
+		{
+			name:   "too-long",
+			retry:  1,
+			status: http.StatusTooManyRequests,
+			header: http.Header{"Retry-After": {"3600"}},
+			min:    time.Hour,
+			max:    time.Hour,
+			ok:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := policy.delay(tt.retry, tt.status, tt.header)
+			if ok != tt.ok {
+				t.Errorf("delay allowed = %v, want %v", ok, tt.ok)
+			}
+			if d < tt.min || d > tt.max {
+				t.Errorf("delay = %s, want it in [%s, %s]", d, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyMethodAllowed(t *testing.T) {
+	tests := []struct {
+		method  string
+		methods []string
+		want    bool
+	}{
+		{http.MethodGet, nil, true},
+		{http.MethodHead, nil, true},
+		{http.MethodPut, nil, true},
+		{http.MethodDelete, nil, true},
+		{http.MethodPost, nil, false},
+		{http.MethodPatch, nil, false},
+		{http.MethodPost, []string{http.MethodPost}, true},
+		{http.MethodGet, []string{http.MethodPost}, false},
+	}
+	for _, tt := range tests {
+		policy := &RetryPolicy{RetryMethods: tt.methods}
+		if got := policy.methodAllowed(tt.method); got != tt.want {
+			t.Errorf("methodAllowed(%s) with %v = %v, want %v", tt.method, tt.methods, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyStatusAllowed(t *testing.T) {
+	tests := []struct {
+		status   int
+		statuses []int
+		want     bool
+	}{
+		{http.StatusTooManyRequests, nil, true},
+		{http.StatusBadGateway, nil, true},
+		{http.StatusServiceUnavailable, nil, true},
+		{http.StatusGatewayTimeout, nil, true},
+		{http.StatusInternalServerError, nil, false},
+		{http.StatusNotFound, nil, false},
+		{http.StatusInternalServerError, []int{http.StatusInternalServerError}, true},
+		{http.StatusTooManyRequests, []int{http.StatusInternalServerError}, false},
+	}
+	for _, tt := range tests {
+		policy := &RetryPolicy{RetryStatuses: tt.statuses}
+		if got := policy.statusAllowed(tt.status); got != tt.want {
+			t.Errorf("statusAllowed(%d) with %v = %v, want %v", tt.status, tt.statuses, got, tt.want)
+		}
+	}
+}
+
+// failingServer responds with the given status to the first failures requests and with 200 to the rest ones.
+// Bodies of requests are collected
+func failingServer(t *testing.T, failures int, status int, header http.Header) (*httptest.Server, *[]string) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, string(body))
+		if len(bodies) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func sendJSON(ctx context.Context, srv *httptest.Server, policy *RetryPolicy, method string, opts ...Option) error {
+	access := newAPIAccess(srv.URL, append([]Option{WithRetryPolicy(policy)}, opts...)...)
+	resp, err := access.makeJSONRequest(ctx, method, "/projects", PrivateToken("token"), map[string]string{"name": "p"})
+	if err != nil {
+		return err
+	}
+	closeBody(ctx, resp)
+	return nil
+}
+
+func TestSendAttempts(t *testing.T) {
+	srv, bodies := failingServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	err := sendJSON(context.Background(), srv, &RetryPolicy{MaxAttempts: 3}, http.MethodGet)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got error %v, expected 503 response error", err)
+	}
+	if len(*bodies) != 3 {
+		t.Errorf("got %d attempts, want 3", len(*bodies))
+	}
+
+	// statuses out of the policy are not retried
+	srv, bodies = failingServer(t, 10, http.StatusInternalServerError, nil)
+	if err := sendJSON(context.Background(), srv, &RetryPolicy{MaxAttempts: 3}, http.MethodGet); err == nil {
+		t.Fatal("error expected")
+	}
+	if len(*bodies) != 1 {
+		t.Errorf("got %d attempts, want 1", len(*bodies))
+	}
+}
+
+func TestSendMethods(t *testing.T) {
+	tests := []struct {
+		method   string
+		attempts int
+	}{
+		{http.MethodGet, 2},
+		{http.MethodPut, 2},
+		{http.MethodPost, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			srv, bodies := failingServer(t, 1, http.StatusBadGateway, nil)
+			err := sendJSON(context.Background(), srv, &RetryPolicy{MaxAttempts: 3}, tt.method)
+			if tt.attempts > 1 && err != nil {
+				t.Fatal(err)
+			}
+			if tt.attempts == 1 && err == nil {
+				t.Fatal("requests which are not retried must fail")
+			}
+			if len(*bodies) != tt.attempts {
+				t.Errorf("got %d attempts, want %d", len(*bodies), tt.attempts)
+			}
+
+			// the body is rewound for every attempt
+			for i, body := range *bodies {
+				if body != `{"name":"p"}` {
+					t.Errorf("attempt %d got body %q", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestSendRetryAfter(t *testing.T) {
+	srv, bodies := failingServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	start := time.Now()
+	if err := sendJSON(context.Background(), srv, &RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond}, http.MethodGet); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried in %s despite Retry-After of 1s", elapsed)
+	}
+	if len(*bodies) != 2 {
+		t.Errorf("got %d attempts, want 2", len(*bodies))
+	}
+}
+
+func TestSendCancelDuringBackoff(t *testing.T) {
+	srv, _ := failingServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	// cancel shortly after the first attempt, when the client waits to retry
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var attempts int
+	hook := WithResponseHook(func(req *http.Request, resp *http.Response, err error) {
+		attempts++
+		time.AfterFunc(10*time.Millisecond, cancel)
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sendJSON(ctx, srv, &RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Hour}, http.MethodGet, hook)
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, expected context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request is not canceled during backoff")
+	}
+	if attempts != 1 {
+		t.Errorf("got %d attempts, want 1", attempts)
+	}
+}
@@ -0,0 +1,150 @@
+package gitlab
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// Version is a semantic version, see https://semver.org
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+
+	// Prefix is a part of the tag before the version, e.g. sub/module/ for sub/module/v1.2.3 tag
+	Prefix string
+
+	// Tag is a tag the version was taken from, it is nil for versions parsed with ParseVersion
+	Tag *gitlabdata.Tag
+}
+
+// ParseVersion parses semantic version with or without v prefix: v1.2.3, 1.2.3-rc.1+build.5, etc
+func ParseVersion(value string) (*Version, error) {
+	v, ok := parseVersion(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid semantic version %s", value)
+	}
+	return v, nil
+}
+
+// ParseTagVersion parses a tag name with optional subdirectory prefix like sub/module/v1.2.3
+func ParseTagVersion(tag string) (*Version, error) {
+	prefix, value := splitTagPrefix(tag)
+	v, err := ParseVersion(value)
+	if err != nil {
+		return nil, err
+	}
+	v.Prefix = prefix
+	return v, nil
+}
+
+// splitTagPrefix splits tag name into the subdirectory prefix (with trailing slash) and the rest
+func splitTagPrefix(tag string) (prefix string, rest string) {
+	if pos := strings.LastIndexByte(tag, '/'); pos >= 0 {
+		return tag[:pos+1], tag[pos+1:]
+	}
+	return "", tag
+}
+
+func parseVersion(value string) (*Version, bool) {
+	value = "v" + strings.TrimPrefix(value, "v")
+
+	// shorthands like v1.2 are valid for semver package, they are not versions of tags though
+	if !semver.IsValid(value) || semver.Canonical(value) != strings.TrimSuffix(value, semver.Build(value)) {
+		return nil, false
+	}
+
+	v := Version{
+		Prerelease: strings.TrimPrefix(semver.Prerelease(value), "-"),
+		Build:      strings.TrimPrefix(semver.Build(value), "+"),
+	}
+	core := strings.TrimSuffix(strings.TrimSuffix(value, semver.Build(value)), semver.Prerelease(value))
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range strings.Split(core[1:], ".") {
+		num, err := strconv.Atoi(part)
+		if err != nil {
+			// too large to be represented
+			return nil, false
+		}
+		*numbers[i] = num
+	}
+
+	return &v, true
+}
+
+// String returns canonical representation of the version with v prefix and without subdirectory prefix
+func (v *Version) String() string {
+	res := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		res += "-" + v.Prerelease
+	}
+	if len(v.Build) > 0 {
+		res += "+" + v.Build
+	}
+	return res
+}
+
+// IsPrerelease checks if this is a pre-release version
+func (v *Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare compares versions according to semver precedence rules, build metadata is ignored.
+// Returns -1, 0 or 1 if v is less than, equal to or greater than other respectively
+func (v *Version) Compare(other *Version) int {
+	return semver.Compare(v.String(), other.String())
+}
+
+// SortTagsBySemver sorts tags by semantic versions in ascending order. Tags with different subdirectory prefixes
+// are ordered by prefix first. Tags which are not semantic versions go first in name order
+func SortTagsBySemver(tags []*gitlabdata.Tag) {
+	versions := make([]*Version, len(tags))
+	for i, tag := range tags {
+		v, err := ParseTagVersion(tag.Name)
+		if err == nil {
+			versions[i] = v
+		}
+	}
+
+	sort.Stable(tagsBySemver{tags: tags, versions: versions})
+}
+
+type tagsBySemver struct {
+	tags     []*gitlabdata.Tag
+	versions []*Version
+}
+
+func (s tagsBySemver) Len() int {
+	return len(s.tags)
+}
+
+func (s tagsBySemver) Less(i, j int) bool {
+	vi, vj := s.versions[i], s.versions[j]
+	switch {
+	case vi == nil && vj == nil:
+		return s.tags[i].Name < s.tags[j].Name
+	case vi == nil:
+		return true
+	case vj == nil:
+		return false
+	case vi.Prefix != vj.Prefix:
+		return vi.Prefix < vj.Prefix
+	}
+	if res := vi.Compare(vj); res != 0 {
+		return res < 0
+	}
+	return s.tags[i].Name < s.tags[j].Name
+}
+
+func (s tagsBySemver) Swap(i, j int) {
+	s.tags[i], s.tags[j] = s.tags[j], s.tags[i]
+	s.versions[i], s.versions[j] = s.versions[j], s.versions[i]
+}
@@ -0,0 +1,211 @@
+package gitlab
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *Version
+		wantErr bool
+	}{
+		{value: "v1.2.3", want: &Version{Major: 1, Minor: 2, Patch: 3}},
+		{value: "1.2.3", want: &Version{Major: 1, Minor: 2, Patch: 3}},
+		{value: "v0.0.0", want: &Version{}},
+		{value: "v1.2.3-rc.1", want: &Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"}},
+		{value: "v1.2.3-rc.1+build.5", want: &Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "build.5"}},
+		{value: "v1.2.3+build", want: &Version{Major: 1, Minor: 2, Patch: 3, Build: "build"}},
+		{value: "v1.0.0--1", want: &Version{Major: 1, Prerelease: "-1"}},
+		{value: "v10.20.30", want: &Version{Major: 10, Minor: 20, Patch: 30}},
+		{value: "v1.2", wantErr: true},
+		{value: "v1", wantErr: true},
+		{value: "v1.2.3.4", wantErr: true},
+		{value: "v01.2.3", wantErr: true},
+		{value: "v1.2.3-01", wantErr: true},
+		{value: "v1.2.3-", wantErr: true},
+		{value: "v1.2.3-rc..1", wantErr: true},
+		{value: "v1.2.3-rc_1", wantErr: true},
+		{value: "vv1.2.3", wantErr: true},
+		{value: "release", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "v99999999999999999999.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseVersion(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagVersion(t *testing.T) {
+	v, err := ParseTagVersion("sub/module/v1.2.3-beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Prefix != "sub/module/" || v.String() != "v1.2.3-beta" {
+		t.Errorf("unexpected version %s with prefix %s", v, v.Prefix)
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3", "v1.2.4", -1},
+		{"v1.3.0", "v1.2.9", 1},
+		{"v2.0.0", "v10.0.0", -1},
+		{"v1.0.0-rc.1", "v1.0.0", -1},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1", -1},
+		{"v1.0.0-alpha.1", "v1.0.0-alpha.beta", -1},
+		{"v1.0.0-alpha.beta", "v1.0.0-beta", -1},
+		{"v1.0.0-beta.2", "v1.0.0-beta.11", -1},
+		{"v1.0.0-rc.1", "v1.0.0-rc.1+build", 0},
+		{"v1.0.0+a", "v1.0.0+b", 0},
+		// -1 is an alphanumeric identifier, so it has higher precedence than numeric ones
+		{"v1.0.0-1", "v1.0.0--1", -1},
+		{"v1.0.0--1", "v1.0.0-0", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			a, err := ParseVersion(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseVersion(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.Compare(a); got != -tt.want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		miss       []string
+		wantErr    bool
+	}{
+		{constraint: "", match: []string{"v0.0.1", "v1.2.3", "v2.0.0-rc.1"}},
+		{constraint: "*", match: []string{"v0.0.1", "v1.2.3"}},
+		{constraint: "latest", match: []string{"v1.2.3"}},
+		{constraint: "v1.2.3", match: []string{"v1.2.3", "v1.2.3+build"}, miss: []string{"v1.2.4", "v1.2.3-rc.1"}},
+		{constraint: "1.2.3", match: []string{"v1.2.3"}, miss: []string{"v1.2.2"}},
+		{constraint: "=v1.2.3", match: []string{"v1.2.3"}, miss: []string{"v1.2.4"}},
+		{constraint: "v1.2", match: []string{"v1.2.0", "v1.2.99"}, miss: []string{"v1.3.0", "v1.1.9", "v1.3.0-rc.1"}},
+		{constraint: "v1", match: []string{"v1.0.0", "v1.99.0"}, miss: []string{"v2.0.0", "v0.9.0", "v2.0.0-rc.1"}},
+		{constraint: ">v1.2.3", match: []string{"v1.2.4", "v2.0.0"}, miss: []string{"v1.2.3", "v1.2.3-rc.1"}},
+		{constraint: ">=v1.2", match: []string{"v1.2.0", "v1.3.0"}, miss: []string{"v1.1.9", "v1.2.0-rc.1"}},
+		{constraint: "<v1.2.3", match: []string{"v1.2.2", "v1.2.3-rc.1"}, miss: []string{"v1.2.3"}},
+		{constraint: "<=v1.2.3", match: []string{"v1.2.3"}, miss: []string{"v1.2.4"}},
+		{constraint: ">=v1.2.0, <v1.4.0", match: []string{"v1.2.0", "v1.3.9"}, miss: []string{"v1.4.0", "v1.1.0"}},
+		{constraint: ">=v1.2.0 <v1.4.0", match: []string{"v1.3.0"}, miss: []string{"v1.4.0"}},
+		{constraint: "^v1.2.3", match: []string{"v1.2.3", "v1.9.0"}, miss: []string{"v1.2.2", "v2.0.0", "v2.0.0-rc.1"}},
+		{constraint: "^v0.2.3", match: []string{"v0.2.3", "v0.2.9"}, miss: []string{"v0.3.0", "v0.2.2"}},
+		{constraint: "^v0.0.3", match: []string{"v0.0.3"}, miss: []string{"v0.0.4"}},
+		{constraint: "^v0", match: []string{"v0.0.1", "v0.9.0"}, miss: []string{"v1.0.0"}},
+		{constraint: "~v1.2.3", match: []string{"v1.2.3", "v1.2.9"}, miss: []string{"v1.3.0", "v1.2.2"}},
+		{constraint: "~v1", match: []string{"v1.5.0"}, miss: []string{"v2.0.0"}},
+		{constraint: "^v1.2.3-rc.1", match: []string{"v1.2.3-rc.1", "v1.2.3", "v1.5.0"}, miss: []string{"v1.2.3-beta"}},
+		{constraint: "v1.2.x", wantErr: true},
+		{constraint: "v1 || v2", wantErr: true},
+		{constraint: "!=v1.2.3", wantErr: true},
+		{constraint: ">=", wantErr: true},
+		{constraint: "^v1.2-rc", wantErr: true},
+		{constraint: "junk", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConstraint(%q) error = %v, wantErr %v", tt.constraint, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for _, value := range tt.match {
+				if !c.Check(mustParseVersion(t, value)) {
+					t.Errorf("%s must satisfy %q", value, tt.constraint)
+				}
+			}
+			for _, value := range tt.miss {
+				if c.Check(mustParseVersion(t, value)) {
+					t.Errorf("%s must not satisfy %q", value, tt.constraint)
+				}
+			}
+		})
+	}
+}
+
+func TestSortTagsBySemver(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{
+			name: "versions",
+			tags: []string{"v1.10.0", "v1.2.0", "v1.2.0-rc.1", "v0.9.0", "v1.2.0-beta"},
+			want: []string{"v0.9.0", "v1.2.0-beta", "v1.2.0-rc.1", "v1.2.0", "v1.10.0"},
+		},
+		{
+			name: "non-versions-first",
+			tags: []string{"v1.0.0", "release", "v0.1.0", "alpha"},
+			want: []string{"alpha", "release", "v0.1.0", "v1.0.0"},
+		},
+		{
+			name: "prefixes",
+			tags: []string{"sub/v1.0.0", "v2.0.0", "sub/v0.1.0", "v1.0.0"},
+			want: []string{"v1.0.0", "v2.0.0", "sub/v0.1.0", "sub/v1.0.0"},
+		},
+		{
+			name: "build-metadata",
+			tags: []string{"v1.0.0+b", "v1.0.0+a", "1.0.0"},
+			want: []string{"1.0.0", "v1.0.0+a", "v1.0.0+b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := make([]*gitlabdata.Tag, len(tt.tags))
+			for i, name := range tt.tags {
+				tags[i] = &gitlabdata.Tag{Name: name}
+			}
+			SortTagsBySemver(tags)
+
+			got := make([]string, len(tags))
+			for i, tag := range tags {
+				got[i] = tag.Name
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortTagsBySemver(%v) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func mustParseVersion(t *testing.T, value string) *Version {
+	t.Helper()
+	v, err := ParseVersion(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,131 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+func TestListTags(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/tags": `[{"name":"v1.2.0","commit":{"id":"bbb"}},{"name":"v1.1.0","commit":{"id":"aaa"}}]`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	tags, err := client.ListTags(context.Background(), "g/p", &gitlabdata.ListTagsOptions{
+		Search:  gitlabdata.String("^v1."),
+		OrderBy: gitlabdata.String(gitlabdata.TagsOrderByVersion),
+		Sort:    gitlabdata.String(gitlabdata.SortDesc),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].Name != "v1.2.0" || tags[0].Commit.ID != "bbb" || tags[1].Name != "v1.1.0" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/tags",
+		url.Values{"search": {"^v1."}, "order_by": {"version"}, "sort": {"desc"}})
+
+	// nil options request all tags in the default order
+	iter := client.ListTagsIter(context.Background(), "g/p", nil)
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Value().Name)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Errorf("got tags %v", names)
+	}
+	checkRequest(t, (*requests)[1], http.MethodGet, "/projects/g%2Fp/repository/tags", url.Values{})
+}
+
+func TestListTagsEmpty(t *testing.T) {
+	srv, _ := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/tags": `[]`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	// no matches is not an error
+	tags, err := client.ListTags(context.Background(), "g/p", &gitlabdata.ListTagsOptions{Search: gitlabdata.String("^v9.")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("got tags %v", tags)
+	}
+}
+
+func TestTag(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/tags/release%2Fv1.0.0": `{"name":"release/v1.0.0","commit":{"id":"aaa"}}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	tag, err := client.Tag(context.Background(), "g/p", "release/v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.Name != "release/v1.0.0" || tag.Commit.ID != "aaa" {
+		t.Errorf("unexpected tag %+v", tag)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/tags/release%2Fv1.0.0", url.Values{})
+
+	// Tags treats the prefix as an exact tag name
+	tags, err := client.Tags(context.Background(), "g/p", "release/v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Name != "release/v1.0.0" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	checkRequest(t, (*requests)[1], http.MethodGet, "/projects/g%2Fp/repository/tags/release%2Fv1.0.0", url.Values{})
+
+	if _, err := client.Tags(context.Background(), "g/p", "release"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found for a prefix of the tag", err)
+	}
+}
+
+func TestCreateTag(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"POST /projects/g%2Fp/repository/tags": `{"name":"release/v1.0.0","message":"first release","target":"aaa","commit":{"id":"aaa"}}`,
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	tag, err := client.CreateTag(context.Background(), "g/p", "release/v1.0.0", "master", "first release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.Name != "release/v1.0.0" || tag.Message != "first release" || tag.Commit.ID != "aaa" {
+		t.Errorf("unexpected tag %+v", tag)
+	}
+	checkRequest(t, (*requests)[0], http.MethodPost, "/projects/g%2Fp/repository/tags",
+		url.Values{"tag_name": {"release/v1.0.0"}, "ref": {"master"}, "message": {"first release"}})
+
+	// lightweight tags are created without a message
+	if _, err := client.CreateTag(context.Background(), "g/p", "v1.0.1", "aaa", ""); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, (*requests)[1], http.MethodPost, "/projects/g%2Fp/repository/tags",
+		url.Values{"tag_name": {"v1.0.1"}, "ref": {"aaa"}})
+}
+
+func TestDeleteTag(t *testing.T) {
+	srv, requests := apiServer(t, map[string]string{
+		"DELETE /projects/g%2Fp/repository/tags/release%2Fv1.0.0": "",
+	})
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	if err := client.DeleteTag(context.Background(), "g/p", "release/v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, (*requests)[0], http.MethodDelete, "/projects/g%2Fp/repository/tags/release%2Fv1.0.0", url.Values{})
+
+	if err := client.DeleteTag(context.Background(), "g/p", "missing"); !IsNotFound(err) {
+		t.Errorf("got error %v, expected not found", err)
+	}
+}
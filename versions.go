@@ -0,0 +1,240 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// VersionResolver resolves semantic versions of Go modules and other packages out of repository tags.
+// Modules located in subdirectories are versioned with prefixed tags, e.g. sub/module/v1.2.3
+type VersionResolver struct {
+	client Client
+}
+
+// NewVersionResolver creates a resolver using the given client
+func NewVersionResolver(client Client) *VersionResolver {
+	return &VersionResolver{
+		client: client,
+	}
+}
+
+// Versions returns semantic versions of a module located in the given subdirectory of the project, empty
+// modulePathPrefix stands for the root one. Versions are sorted in ascending order, tags which are not semantic
+// versions are skipped
+func (r *VersionResolver) Versions(ctx context.Context, project, modulePathPrefix string) ([]*Version, error) {
+	prefix := strings.Trim(modulePathPrefix, "/")
+	if len(prefix) > 0 {
+		prefix += "/"
+	}
+
+	var opts gitlabdata.ListTagsOptions
+	if len(prefix) > 0 {
+		opts.Search = gitlabdata.String("^" + prefix)
+	}
+
+	var res []*Version
+	tags := r.client.ListTagsIter(ctx, project, &opts)
+	for tags.Next() {
+		tag := tags.Value()
+		tagPrefix, value := splitTagPrefix(tag.Name)
+		if tagPrefix != prefix {
+			continue
+		}
+		v, ok := parseVersion(value)
+		if !ok {
+			continue
+		}
+		v.Prefix = tagPrefix
+		v.Tag = tag
+		res = append(res, v)
+	}
+	if err := tags.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Compare(res[j]) < 0
+	})
+	return res, nil
+}
+
+// LatestVersion returns the highest version satisfying the constraint. Pre-releases are only considered when there
+// are no releases satisfying it. The constraint can be prefixed with a module subdirectory, e.g. sub/module/^1.2,
+// see ParseConstraint for the syntax. Returns an error satisfying errors.Is(err, os.ErrNotExist) if there is no
+// such version
+func (r *VersionResolver) LatestVersion(ctx context.Context, project, constraint string) (*Version, error) {
+	var modulePathPrefix string
+	if pos := strings.LastIndexByte(constraint, '/'); pos >= 0 {
+		modulePathPrefix = constraint[:pos]
+		constraint = constraint[pos+1:]
+	}
+
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := r.Versions(ctx, project, modulePathPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var latestPrerelease *Version
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if !c.Check(v) {
+			continue
+		}
+		if !v.IsPrerelease() {
+			return v, nil
+		}
+		if latestPrerelease == nil {
+			latestPrerelease = v
+		}
+	}
+	if latestPrerelease != nil {
+		return latestPrerelease, nil
+	}
+
+	return nil, fmt.Errorf("no version of %s matches %s: %w", project, constraint, os.ErrNotExist)
+}
+
+// Constraint restricts a set of versions
+type Constraint struct {
+	// all comparators must be satisfied
+	comparators []comparator
+}
+
+type comparator struct {
+	op      string
+	version string // canonical semantic version
+}
+
+// ParseConstraint parses version constraint. It consists of comparators separated with spaces or commas, all of them
+// must be satisfied. Supported comparators are:
+//
+//	=v1.2.3, >v1.2.3, >=v1.2.3, <v1.2.3, <=v1.2.3 - comparisons, missing components are zeroes: >=v1.2
+//	v1.2.3 - exact version
+//	v1.2, v1 - any version with given components
+//	^v1.2.3 - versions compatible with v1.2.3: >=v1.2.3 <v2.0.0, >=v0.2.3 <v0.3.0 for ^v0.2.3
+//	~v1.2.3 - patch updates of v1.2.3: >=v1.2.3 <v1.3.0
+//
+// The v prefix is optional. Empty constraint, * and latest match any version
+func ParseConstraint(constraint string) (*Constraint, error) {
+	items := strings.FieldsFunc(constraint, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t'
+	})
+
+	var res Constraint
+	for _, item := range items {
+		comparators, err := parseComparator(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %s: %w", constraint, err)
+		}
+		res.comparators = append(res.comparators, comparators...)
+	}
+
+	return &res, nil
+}
+
+// Check checks if the version satisfies the constraint
+func (c *Constraint) Check(v *Version) bool {
+	for _, cmp := range c.comparators {
+		if !cmp.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparator) check(v *Version) bool {
+	res := semver.Compare(v.String(), c.version)
+	switch c.op {
+	case "=":
+		return res == 0
+	case ">":
+		return res > 0
+	case ">=":
+		return res >= 0
+	case "<":
+		return res < 0
+	case "<=":
+		return res <= 0
+	default:
+		return false
+	}
+}
+
+func parseComparator(item string) ([]comparator, error) {
+	switch item {
+	case "*", "latest":
+		return nil, nil
+	}
+
+	var op string
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(item, candidate) {
+			op = candidate
+			item = item[len(candidate):]
+			break
+		}
+	}
+
+	// semver package accepts shorthands v1 and v1.2 and turns them into v1.0.0 and v1.2.0
+	value := "v" + strings.TrimPrefix(item, "v")
+	if !semver.IsValid(value) {
+		return nil, fmt.Errorf("invalid version %s", item)
+	}
+	floor := semver.Canonical(value)
+	components := strings.Count(strings.TrimSuffix(value, semver.Build(value)), ".") + 1
+	if len(semver.Prerelease(value)) > 0 {
+		// dots of the pre-release are not version components
+		components = 3
+	}
+
+	switch op {
+	case "":
+		if components == 3 {
+			return []comparator{{op: "=", version: floor}}, nil
+		}
+		return []comparator{{op: ">=", version: floor}, {op: "<", version: ceiling(floor, components)}}, nil
+	case "=", ">", ">=", "<", "<=":
+		return []comparator{{op: op, version: floor}}, nil
+	case "~":
+		if components > 2 {
+			components = 2
+		}
+		return []comparator{{op: ">=", version: floor}, {op: "<", version: ceiling(floor, components)}}, nil
+	case "^":
+		// the first non-zero component must be kept
+		v, _ := parseVersion(floor)
+		switch {
+		case v.Major > 0 || components == 1:
+			components = 1
+		case v.Minor > 0 || components == 2:
+			components = 2
+		}
+		return []comparator{{op: ">=", version: floor}, {op: "<", version: ceiling(floor, components)}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operator %s", op)
+}
+
+// ceiling returns the lowest version above all versions having the same first components as the given one. It is
+// a pre-release to exclude pre-releases of the next version as well
+func ceiling(version string, components int) string {
+	v, _ := parseVersion(version)
+	numbers := []int{v.Major, v.Minor, v.Patch}
+	numbers[components-1]++
+	for i := components; i < 3; i++ {
+		numbers[i] = 0
+	}
+	return fmt.Sprintf("v%d.%d.%d-0", numbers[0], numbers[1], numbers[2])
+}
@@ -0,0 +1,111 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// versionsServer serves tags with the given names
+func versionsServer(t *testing.T, names ...string) (*httptest.Server, *[]apiRequest) {
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = fmt.Sprintf(`{"name":%q}`, name)
+	}
+	return apiServer(t, map[string]string{
+		"GET /projects/g%2Fp/repository/tags": "[" + strings.Join(tags, ",") + "]",
+	})
+}
+
+func TestVersions(t *testing.T) {
+	srv, requests := versionsServer(t, "v1.2.0", "v1.0.0", "not-a-version", "v1.0.0-rc.1", "sub/module/v0.1.0")
+	resolver := NewVersionResolver(NewAPIAccess(nil, srv.URL).Client("token"))
+
+	versions, err := resolver.Versions(context.Background(), "g/p", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Tag.Name)
+	}
+	if strings.Join(got, ",") != "v1.0.0-rc.1,v1.0.0,v1.2.0" {
+		t.Errorf("got versions %v", got)
+	}
+	checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/tags", url.Values{})
+}
+
+func TestLatestVersion(t *testing.T) {
+	tags := []string{
+		"v1.0.0", "v1.2.0", "v1.2.1-rc.1", "v1.3.0-rc.1", "v2.0.0-beta", "release-2020",
+		"sub/module/v0.1.0", "sub/module/v0.1.5", "sub/module/v0.2.0", "sub/module/deeper/v9.0.0",
+	}
+	tests := []struct {
+		constraint string
+		search     string
+		tag        string
+		prefix     string
+	}{
+		{constraint: "", tag: "v1.2.0"},
+		{constraint: "latest", tag: "v1.2.0"},
+		{constraint: "^1", tag: "v1.2.0"},
+		{constraint: "~v1.0.0", tag: "v1.0.0"},
+		{constraint: "<v1.2.0", tag: "v1.0.0"},
+		{constraint: "v1.3", tag: ""},
+		{constraint: ">=v1.2.1-0 <v1.3.0-0", tag: "v1.2.1-rc.1"},
+		{constraint: ">v1.2.0", tag: "v2.0.0-beta"},
+		{constraint: "v3", tag: ""},
+		{constraint: "sub/module/^0.1", search: "^sub/module/", tag: "sub/module/v0.1.5", prefix: "sub/module/"},
+		{constraint: "sub/module/latest", search: "^sub/module/", tag: "sub/module/v0.2.0", prefix: "sub/module/"},
+		{constraint: "sub/module/deeper/*", search: "^sub/module/deeper/", tag: "sub/module/deeper/v9.0.0", prefix: "sub/module/deeper/"},
+		{constraint: "sub/v1", search: "^sub/", tag: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			srv, requests := versionsServer(t, tags...)
+			resolver := NewVersionResolver(NewAPIAccess(nil, srv.URL).Client("token"))
+
+			v, err := resolver.LatestVersion(context.Background(), "g/p", tt.constraint)
+			query := url.Values{}
+			if len(tt.search) > 0 {
+				query.Set("search", tt.search)
+			}
+			if len(*requests) != 1 {
+				t.Fatalf("got %d requests", len(*requests))
+			}
+			checkRequest(t, (*requests)[0], http.MethodGet, "/projects/g%2Fp/repository/tags", query)
+
+			if len(tt.tag) == 0 {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("got version %v and error %v, expected not exist error", v, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Tag == nil || v.Tag.Name != tt.tag || v.Prefix != tt.prefix {
+				t.Errorf("got version %s of tag %v with prefix %q, want %s", v, v.Tag, v.Prefix, tt.tag)
+			}
+		})
+	}
+}
+
+func TestLatestVersionInvalidConstraint(t *testing.T) {
+	srv, requests := versionsServer(t, "v1.0.0")
+	resolver := NewVersionResolver(NewAPIAccess(nil, srv.URL).Client("token"))
+
+	if _, err := resolver.LatestVersion(context.Background(), "g/p", "sub/^x"); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got error %v, expected invalid constraint", err)
+	}
+	if len(*requests) != 0 {
+		t.Error("tags are requested for invalid constraint")
+	}
+}
@@ -0,0 +1,66 @@
+package gitlab
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+// WalkFunc is called by Walk for every visited file or directory. It follows fs.WalkDirFunc semantics: it is
+// called for a directory before its listing and once again with a non-nil err if the listing failed. Returning
+// filepath.SkipDir for a directory skips its content, returning it for a file skips the rest of its directory
+type WalkFunc func(path string, node *gitlabdata.TreeNode, err error) error
+
+// Walk walks the repository tree at the given ref rooted at root in lexical order calling fn for every file and
+// directory, the root included. It makes one non-recursive Tree request per directory when the directory is
+// reached, so skipped directories are not requested at all
+func Walk(ctx context.Context, client Client, project, root, ref string, fn WalkFunc) error {
+	root = strings.Trim(root, "/")
+	node := &gitlabdata.TreeNode{
+		Name: path.Base(root),
+		Type: gitlabdata.TreeNodeTree,
+		Path: root,
+	}
+
+	err := walkTree(ctx, client, project, ref, node, fn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walkTree(ctx context.Context, client Client, project, ref string, dir *gitlabdata.TreeNode, fn WalkFunc) error {
+	if err := fn(dir.Path, dir, nil); err != nil {
+		return err
+	}
+
+	nodes, err := client.Tree(ctx, project, dir.Path, ref, false)
+	if err != nil {
+		return fn(dir.Path, dir, err)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	for _, node := range nodes {
+		if node.Type == gitlabdata.TreeNodeTree {
+			if err := walkTree(ctx, client, project, ref, node, fn); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(node.Path, node, nil); err != nil {
+			if err == filepath.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sirkon/gitlab/gitlabdata"
+)
+
+var testWalkFiles = map[string]string{
+	"a/b.txt":   "b",
+	"a/c/d.txt": "d",
+	"a/e.txt":   "e",
+	"b/f.txt":   "f",
+	"g.txt":     "g",
+	"h/i.txt":   "i",
+}
+
+func TestWalk(t *testing.T) {
+	errStop := errors.New("stop")
+	tests := []struct {
+		name    string
+		root    string
+		stop    map[string]error
+		visited string
+		listed  string
+		err     error
+	}{
+		{
+			name:    "all",
+			visited: ",a,a/b.txt,a/c,a/c/d.txt,a/e.txt,b,b/f.txt,g.txt,h,h/i.txt",
+			listed:  ",a,a/c,b,h",
+		},
+		{
+			name:    "subtree",
+			root:    "/a/",
+			visited: "a,a/b.txt,a/c,a/c/d.txt,a/e.txt",
+			listed:  "a,a/c",
+		},
+		{
+			name:    "skip-dir",
+			stop:    map[string]error{"a/c": fs.SkipDir},
+			visited: ",a,a/b.txt,a/c,a/e.txt,b,b/f.txt,g.txt,h,h/i.txt",
+			listed:  ",a,b,h",
+		},
+		{
+			name:    "skip-file",
+			stop:    map[string]error{"a/b.txt": fs.SkipDir},
+			visited: ",a,a/b.txt,b,b/f.txt,g.txt,h,h/i.txt",
+			listed:  ",a,b,h",
+		},
+		{
+			name:    "skip-root",
+			stop:    map[string]error{"": fs.SkipDir},
+			visited: "",
+			listed:  "",
+		},
+		{
+			name:    "error-on-dir",
+			stop:    map[string]error{"b": errStop},
+			visited: ",a,a/b.txt,a/c,a/c/d.txt,a/e.txt,b",
+			listed:  ",a,a/c",
+			err:     errStop,
+		},
+		{
+			name:    "error-on-file",
+			stop:    map[string]error{"a/c/d.txt": errStop},
+			visited: ",a,a/b.txt,a/c,a/c/d.txt",
+			listed:  ",a,a/c",
+			err:     errStop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := repoServer(t, testWalkFiles)
+			var listed []string
+			hook := WithRequestHook(func(req *http.Request) {
+				if strings.HasSuffix(req.URL.Path, "/repository/tree") {
+					if req.URL.Query().Get("recursive") != "" {
+						t.Errorf("recursive tree request %s", req.URL)
+					}
+					listed = append(listed, req.URL.Query().Get("path"))
+				}
+			})
+			client := NewAPIAccess(nil, srv.URL, hook).Client("token")
+
+			var visited []string
+			err := Walk(context.Background(), client, "g/p", tt.root, "master", func(path string, node *gitlabdata.TreeNode, err error) error {
+				if err != nil {
+					t.Fatalf("unexpected error for %s: %v", path, err)
+				}
+				if node.Path != path {
+					t.Errorf("got node %s for %s", node.Path, path)
+				}
+				visited = append(visited, path)
+				return tt.stop[path]
+			})
+			if err != tt.err {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+			if got := strings.Join(visited, ","); got != tt.visited {
+				t.Errorf("visited\n%s\nwant\n%s", got, tt.visited)
+			}
+			if got := strings.Join(listed, ","); got != tt.listed {
+				t.Errorf("listed directories %q, want %q", got, tt.listed)
+			}
+		})
+	}
+}
+
+func TestWalkListingError(t *testing.T) {
+	srv, _ := repoServer(t, testWalkFiles)
+	client := NewAPIAccess(nil, srv.URL).Client("token")
+
+	// the directory is reported before the listing and once again with the listing error
+	var calls []error
+	err := Walk(context.Background(), client, "g/p", "missing", "master", func(path string, node *gitlabdata.TreeNode, err error) error {
+		if path != "missing" || node.Type != gitlabdata.TreeNodeTree {
+			t.Errorf("unexpected node %s %+v", path, node)
+		}
+		calls = append(calls, err)
+		return err
+	})
+	if len(calls) != 2 || calls[0] != nil || !IsNotFound(calls[1]) {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+	if !IsNotFound(err) {
+		t.Errorf("got error %v, expected the listing error", err)
+	}
+}